@@ -1,15 +1,37 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
 	"wp2hugo/wordpress"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
 	config_no_downloads bool
 	config_no_comments  bool
 	config_output_dir   string
+
+	config_static_uploads_paths bool
+	config_uploads_backlinks    string
+	config_image_hosts          []string
+	config_site_aliases         []string
+	config_redirects            []string
+	config_preserve_permalinks  bool
+	config_unapproved_comments  bool
+	config_pingbacks            string
+	config_comments_formats     []string
+	config_remark42_site        string
+	config_comment_author_urls  string
+	config_anonymize_comments   bool
+	config_anonymize_salt       string
+	config_comments_content     string
+	config_comments_mode        string
+	config_overwrite_config     bool
+	config_overwrite_list_pages bool
+	config_pager_size           int
 )
 
 var exportCmd = &cobra.Command{
@@ -23,6 +45,71 @@ var exportCmd = &cobra.Command{
 		wp.ConfigNoDownloads = config_no_downloads
 		wp.ConfigNoComments = config_no_comments
 		wp.ConfigOutputDir = config_output_dir
+		wp.ConfigStaticUploadsPaths = config_static_uploads_paths
+		wp.ConfigUploadsBacklinks = config_uploads_backlinks
+		wp.ConfigImageHosts = config_image_hosts
+		wp.ConfigSiteAliases = config_site_aliases
+		wp.ConfigRedirectFormats = config_redirects
+		wp.ConfigPreservePermalinks = config_preserve_permalinks
+		wp.ConfigUnapprovedComments = config_unapproved_comments
+		wp.ConfigPingbacks = config_pingbacks
+		wp.ConfigCommentsFormats = config_comments_formats
+		wp.ConfigRemark42Site = config_remark42_site
+		wp.ConfigCommentAuthorUrls = config_comment_author_urls
+		wp.ConfigAnonymizeComments = config_anonymize_comments
+		wp.ConfigAnonymizeSalt = config_anonymize_salt
+		wp.ConfigCommentsContent = config_comments_content
+		wp.ConfigCommentsMode = config_comments_mode
+		wp.ConfigOverwriteConfig = config_overwrite_config
+		wp.ConfigOverwriteListPages = config_overwrite_list_pages
+		wp.ConfigPagerSize = config_pager_size
+
+		// attachment types from config file extend (or override) defaults
+		var attachment_types map[string]wordpress.AttachmentType
+		if err := viper.UnmarshalKey("attachments", &attachment_types); err != nil {
+			return err
+		}
+		for ext, t := range attachment_types {
+			wp.ConfigAttachmentTypes[strings.TrimPrefix(strings.ToLower(ext), ".")] = t
+		}
+
+		// archive types from config file override defaults
+		var archive_types map[string]wordpress.ArchiveType
+		if err := viper.UnmarshalKey("archives", &archive_types); err != nil {
+			return err
+		}
+		for name, t := range archive_types {
+			archive, ok := wp.ConfigArchiveTypes[name]
+			if !ok {
+				return fmt.Errorf("Unknown archive type: %s", name)
+			}
+			if t.Prefix != "" {
+				archive.Prefix = t.Prefix
+			}
+			if t.Target != "" {
+				archive.Target = t.Target
+			}
+			wp.ConfigArchiveTypes[name] = archive
+		}
+
+		// taxonomy names from config file override defaults
+		for domain, name := range viper.GetStringMapString("taxonomies") {
+			wp.ConfigTaxonomyNames[domain] = name
+		}
+
+		// page templates from config file extend (or override) defaults
+		var page_templates map[string]wordpress.PageTemplate
+		if err := viper.UnmarshalKey("page_templates", &page_templates); err != nil {
+			return err
+		}
+		for template, t := range page_templates {
+			wp.ConfigPageTemplates[template] = t
+		}
+
+		// content path templates from config file override defaults
+		for post_type, text := range viper.GetStringMapString("paths") {
+			wp.ConfigPathTemplates[post_type] = text
+		}
 
 		for i := 0; i < len(args); i++ {
 			wp.ReadWpExport(args[i])
@@ -39,5 +126,23 @@ func init() {
 
 	exportCmd.Flags().BoolVarP(&config_no_downloads, "no-downloads", "d", false, "Do not download any media from remote server")
 	exportCmd.Flags().BoolVarP(&config_no_comments, "no-comments", "c", false, "Do not typeset any comments")
+	exportCmd.Flags().BoolVarP(&config_unapproved_comments, "unapproved-comments", "", false, "Typeset also unapproved comments")
+	exportCmd.Flags().StringVarP(&config_pingbacks, "pingbacks", "", wordpress.PINGBACKS_SEPARATE, "Typeset pingbacks and trackbacks (none, separate, inline)")
+	exportCmd.Flags().StringSliceVarP(&config_comments_formats, "comments-format", "", []string{wordpress.COMMENTS_YAML}, "Formats of comments export (yaml, disqus, isso, remark42, staticman)")
+	exportCmd.Flags().StringVarP(&config_comment_author_urls, "comment-author-urls", "", wordpress.COMMENT_URLS_NOFOLLOW, "Urls of comment authors (none, nofollow, follow)")
+	exportCmd.Flags().BoolVarP(&config_anonymize_comments, "anonymize-comments", "", false, "Replace names of comment authors by pseudonyms")
+	exportCmd.Flags().StringVarP(&config_anonymize_salt, "anonymize-salt", "", "", "Secret salt of comment pseudonyms (random one is used if empty)")
+	exportCmd.Flags().StringVarP(&config_comments_content, "comments-content", "", wordpress.COMMENTS_CONTENT_MARKDOWN, "Format of comments content (markdown, html)")
+	exportCmd.Flags().StringVarP(&config_comments_mode, "comments-mode", "", wordpress.COMMENTS_MODE_BUNDLE, "Placement of yaml comments (bundle, body, data, frontmatter)")
+	exportCmd.Flags().StringVarP(&config_remark42_site, "remark42-site", "", "remark", "Site id used in remark42 comments backup")
+	exportCmd.Flags().BoolVarP(&config_overwrite_config, "overwrite-config", "", false, "Overwrite existing site config")
+	exportCmd.Flags().BoolVarP(&config_overwrite_list_pages, "overwrite-list-pages", "", false, "Overwrite existing generated list pages of sections and terms")
+	exportCmd.Flags().IntVarP(&config_pager_size, "pager-size", "", 10, "Number of items per page of lists in site config")
 	exportCmd.Flags().StringVarP(&config_output_dir, "output-dir", "o", "build", "Output directory")
+	exportCmd.Flags().BoolVarP(&config_static_uploads_paths, "static-uploads-paths", "", false, "Preserve wp-content/uploads structure for media exported to static dir")
+	exportCmd.Flags().StringVarP(&config_uploads_backlinks, "uploads-backlinks", "", wordpress.UPLOADS_BACKLINKS_NONE, "Keep original upload urls working (none, copy, symlink, redirect)")
+	exportCmd.Flags().StringSliceVarP(&config_image_hosts, "image-hosts", "", nil, "Additional hosts to download embedded images from")
+	exportCmd.Flags().StringSliceVarP(&config_site_aliases, "site-aliases", "", nil, "Other hosts (or urls) of this site, links to them are treated as internal")
+	exportCmd.Flags().StringSliceVarP(&config_redirects, "redirects", "", nil, "Generate redirects from old permalinks (aliases, netlify, nginx, apache, csv)")
+	exportCmd.Flags().BoolVarP(&config_preserve_permalinks, "preserve-permalinks", "", false, "Keep original wordpress permalinks as urls of posts and pages")
 }
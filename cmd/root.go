@@ -20,7 +20,8 @@ const (
 
 // global params (flags)
 var (
-	logLevel string
+	logLevel   string
+	configFile string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -52,6 +53,8 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "", "INFO", "Log level (CRITICIAL, ERROR, WARNING, NOTICE, INFO, DEBUG)")
 	viper.BindPFlag("log.level", rootCmd.PersistentFlags().Lookup("log-level"))
+
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "", "", "Config file (yaml, toml or json)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -62,6 +65,15 @@ func initConfig() {
 	viper.SetEnvKeyReplacer(replacer)
 	viper.AutomaticEnv() // read in environment variables that match
 
+	// read config file if provided
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+		if err := viper.ReadInConfig(); err != nil {
+			fmt.Printf("Reading config file \"%s\" failed: %v\n", configFile, err)
+			os.Exit(1)
+		}
+	}
+
 	// configure logging
 	var logLevelStr = viper.GetString("log.level")
 	// try to convert string log level
@@ -0,0 +1,114 @@
+package wordpress
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// names of wordpress archive types
+const (
+	ARCHIVE_CATEGORY = "category"
+	ARCHIVE_TAG      = "tag"
+	ARCHIVE_AUTHOR   = "author"
+	ARCHIVE_YEAR     = "year"
+	ARCHIVE_MONTH    = "month"
+	ARCHIVE_DAY      = "day"
+	ARCHIVE_HOME     = "home"
+)
+
+// ArchiveType describes how links to wordpress archive pages are mapped to
+// hugo list pages. Prefix is wordpress path prefix (used for taxonomy and
+// author archives only), Target is hugo path which could contain
+// placeholders :slug, :year, :month and :day
+type ArchiveType struct {
+	Prefix string `mapstructure:"prefix"`
+	Target string `mapstructure:"target"`
+}
+
+// DefaultArchiveTypes returns mapping of wordpress archives to hugo taxonomy
+// term pages (including authors taxonomy) and year sections
+func DefaultArchiveTypes() map[string]ArchiveType {
+	return map[string]ArchiveType{
+		ARCHIVE_CATEGORY: {Prefix: "/category/", Target: "/categories/:slug/"},
+		ARCHIVE_TAG:      {Prefix: "/tag/", Target: "/tags/:slug/"},
+		ARCHIVE_AUTHOR:   {Prefix: "/author/", Target: "/authors/:slug/"},
+		ARCHIVE_YEAR:     {Target: "/posts/:year/"},
+		ARCHIVE_MONTH:    {Target: "/posts/:year/"},
+		ARCHIVE_DAY:      {Target: "/posts/:year/"},
+		ARCHIVE_HOME:     {Target: "/"},
+	}
+}
+
+// paginated archives, e.g. /category/music/page/2/
+var archive_paging = regexp.MustCompile(`^(.*)/page/(\d+)/?$`)
+
+// date archives, e.g. /2019/, /2019/05/ or /2019/05/02/
+var archive_date = regexp.MustCompile(`^/(\d{4})(?:/(\d{2}))?(?:/(\d{2}))?/?$`)
+
+// links to archive pages (taxonomies, authors, dates and paging) point to
+// hugo list pages
+func (w *WpExport) rewriteArchiveLink(ctx *LinkContext) (string, bool) {
+	if !ctx.Internal || ctx.Selection.Is("img") || ctx.Url.RawQuery != "" {
+		return "", false
+	}
+
+	target, ok := w.getArchiveTarget(ctx.Url.Path)
+	if !ok {
+		return "", false
+	}
+
+	if ctx.Url.Fragment != "" {
+		target += "#" + ctx.Url.Fragment
+	}
+
+	return target, true
+}
+
+// get hugo path of list page corresponding to wordpress archive path
+func (w *WpExport) getArchiveTarget(archive_path string) (string, bool) {
+
+	// hugo uses the same pagination path as wordpress by default
+	paging := ""
+	if parts := archive_paging.FindStringSubmatch(archive_path); parts != nil {
+		archive_path = parts[1]
+		paging = "page/" + parts[2] + "/"
+	}
+
+	var target string
+
+	if parts := archive_date.FindStringSubmatch(archive_path); parts != nil {
+		archive := ARCHIVE_YEAR
+		if parts[3] != "" {
+			archive = ARCHIVE_DAY
+		} else if parts[2] != "" {
+			archive = ARCHIVE_MONTH
+		}
+
+		target = strings.NewReplacer(":year", parts[1], ":month", parts[2], ":day", parts[3]).Replace(w.ConfigArchiveTypes[archive].Target)
+	} else if archive_path == "" || archive_path == "/" {
+		target = w.ConfigArchiveTypes[ARCHIVE_HOME].Target
+	} else {
+		for _, archive := range []string{ARCHIVE_CATEGORY, ARCHIVE_TAG, ARCHIVE_AUTHOR} {
+			t := w.ConfigArchiveTypes[archive]
+			if t.Prefix == "" || !strings.HasPrefix(archive_path, t.Prefix) {
+				continue
+			}
+
+			// hierarchical archives are referenced by the last term
+			slug := path.Base(strings.TrimSuffix(archive_path, "/"))
+			target = strings.ReplaceAll(t.Target, ":slug", slug)
+			break
+		}
+	}
+
+	if target == "" {
+		return "", false
+	}
+
+	if !strings.HasSuffix(target, "/") {
+		target += "/"
+	}
+
+	return target + paging, true
+}
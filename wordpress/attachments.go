@@ -0,0 +1,352 @@
+package wordpress
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// AttachmentType describes where attachments of given file type are stored
+// in page bundle and what kind of resource they represent
+type AttachmentType struct {
+	Dir  string `mapstructure:"dir"`
+	Kind string `mapstructure:"kind"`
+}
+
+// modes of keeping original upload urls (wp-content/uploads/...) working
+const (
+	UPLOADS_BACKLINKS_NONE     = "none"
+	UPLOADS_BACKLINKS_COPY     = "copy"
+	UPLOADS_BACKLINKS_SYMLINK  = "symlink"
+	UPLOADS_BACKLINKS_REDIRECT = "redirect"
+)
+
+// type used for attachments with extensions not present in configuration
+var ATTACHMENT_DEFAULT_TYPE = AttachmentType{Dir: "files", Kind: "file"}
+
+// DefaultAttachmentTypes returns table of file extensions (lower case, without
+// leading dot) mapped to bundle subdirectories and resource kinds
+func DefaultAttachmentTypes() map[string]AttachmentType {
+	result := map[string]AttachmentType{}
+
+	groups := []struct {
+		t          AttachmentType
+		extensions []string
+	}{
+		{AttachmentType{Dir: ITEM_IMAGES_DIR, Kind: "image"}, []string{"jpg", "jpeg", "png", "gif", "webp", "svg", "bmp", "tif", "tiff", "ico"}},
+		{AttachmentType{Dir: "audio", Kind: "audio"}, []string{"mp3", "ogg", "oga", "wav", "m4a", "flac", "aac", "wma"}},
+		{AttachmentType{Dir: "video", Kind: "video"}, []string{"mp4", "m4v", "mov", "webm", "ogv", "avi", "wmv", "mkv", "flv", "3gp"}},
+		{AttachmentType{Dir: "docs", Kind: "document"}, []string{"pdf", "doc", "docx", "odt", "rtf", "txt", "xls", "xlsx", "ods", "csv", "ppt", "pptx", "odp"}},
+		{AttachmentType{Dir: "archives", Kind: "archive"}, []string{"zip", "rar", "7z", "tar", "gz", "tgz", "bz2", "xz"}},
+		{AttachmentType{Dir: "gpx", Kind: "gpx"}, []string{"gpx", "kml", "kmz"}},
+	}
+
+	for i := 0; i < len(groups); i++ {
+		for j := 0; j < len(groups[i].extensions); j++ {
+			result[groups[i].extensions[j]] = groups[i].t
+		}
+	}
+
+	return result
+}
+
+// get type (bundle subdirectory and resource kind) of attachment according
+// to its file extension, unknown extensions fall back to default type
+func (w *WpExport) getAttachmentType(file_name string) AttachmentType {
+	file_ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(file_name)), ".")
+
+	if t, ok := w.ConfigAttachmentTypes[file_ext]; ok {
+		return t
+	}
+
+	w.log.Warningf("Unknown attachment type %s, storing it as %s", file_name, ATTACHMENT_DEFAULT_TYPE.Kind)
+
+	return ATTACHMENT_DEFAULT_TYPE
+}
+
+// look for attachments which are not attached to any exported item (post or
+// page) and store them in static dir, so links to them could be fixed
+func (w *WpExport) prepareStaticMedia() {
+
+	// site paths of stored media mapped to their canonical urls
+	used_paths := map[string]string{}
+
+	ch := w.channel
+	for i := 0; i < len(ch.Items); i++ {
+		a := ch.Items[i]
+
+		if a.Type != "attachment" {
+			continue
+		}
+
+		// skip attachments which are processed as part of some bundle
+		if a.ParentId != 0 {
+			parent := w.FindItem(a.ParentId)
+			if parent != nil && w.IsExported(parent) {
+				continue
+			}
+		}
+
+		media_url, err := url.Parse(a.AttachmentUrl)
+		w.check(err)
+		canonical_url := w.getCanonicalUrl(media_url)
+
+		// the same file name could be uploaded in different months, paths
+		// flattened by type get numeric suffix then
+		site_path := w.getStaticMediaPath(a.AttachmentUrl)
+		base_path := site_path
+		for n := 2; ; n++ {
+			other, used := used_paths[site_path]
+			if !used || other == canonical_url {
+				break
+			}
+			ext := path.Ext(base_path)
+			site_path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base_path, ext), n, ext)
+			w.log.Warningf("Static path of %s collides with %s, trying %s", a.AttachmentUrl, other, site_path)
+		}
+		used_paths[site_path] = canonical_url
+
+		w.log.Debugf("Processing orphan attachment %s -> %s", a.AttachmentUrl, site_path)
+
+		target_file_path := filepath.Join(w.hugo_static, filepath.FromSlash(site_path))
+		w.ensure_dir(filepath.Dir(target_file_path))
+
+		// fetch file and store it
+		w.downloadFile(a.AttachmentUrl, target_file_path)
+
+		// media are indexed by canonical urls to match links to any site host
+		w.static_media[canonical_url] = site_path
+
+		// keep original upload url working
+		if site_path != w.getUploadsPath(a.AttachmentUrl) {
+			w.prepareUploadsBacklink(a.AttachmentUrl, target_file_path, site_path)
+		}
+	}
+
+	w.log.Infof("Orphan attachments exported to static dir: %d", len(w.static_media))
+}
+
+// get path (relative to site root) of media stored in static dir, either
+// preserving original uploads structure or derived from attachment type
+func (w *WpExport) getStaticMediaPath(media_url string) string {
+
+	if w.ConfigStaticUploadsPaths {
+		if uploads_path := w.getUploadsPath(media_url); uploads_path != "" {
+			return uploads_path
+		}
+		w.log.Warningf("Attachment %s is not located in wp-content, storing it by type", media_url)
+	}
+
+	file_name := path.Base(media_url)
+	attachment_type := w.getAttachmentType(file_name)
+
+	return "/" + path.Join(attachment_type.Dir, strings.ToLower(file_name))
+}
+
+// look for images embedded in item content which are not attachments of
+// given item, download images hosted on this site or on allowed hosts into
+// item bundle and add them to resources. Returns map of image urls (as used
+// in content) to bundle paths
+func (w *WpExport) prepareItemEmbeddedImages(item *Item, fm *HugoFrontMatter, item_dir string) map[string]string {
+
+	result := map[string]string{}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(item.Content))
+	w.check(err)
+
+	site_url, err := url.Parse(w.channel.Link)
+	w.check(err)
+
+	// urls of attachments which are already part of the bundle
+	attached := map[string]bool{}
+	attachments := w.FindAttachments(item.Id)
+	for i := 0; i < len(attachments); i++ {
+		attached[attachments[i].AttachmentUrl] = true
+	}
+
+	doc.Find("img[src]").Each(func(i int, img *goquery.Selection) {
+		src, _ := img.Attr("src")
+
+		if _, ok := result[src]; ok || attached[src] {
+			return
+		}
+
+		img_url, err := site_url.Parse(src)
+		if err != nil {
+			w.log.Warningf("Invalid image url %s in %s (%d)", src, item.Title, item.Id)
+			return
+		}
+
+		if _, ok := w.static_media[w.getCanonicalUrl(img_url)]; ok {
+			return
+		}
+
+		if !w.isSiteHost(img_url.Host) && !w.isImageHost(img_url.Host) {
+			w.log.Debugf("Skipping embedded image %s from foreign host", src)
+			return
+		}
+
+		// resized variant of attached image
+		if w.isSiteHost(img_url.Host) && w.findUploadResource(fm, img_url) != nil {
+			return
+		}
+
+		file_name := strings.ToLower(path.Base(img_url.Path))
+		if path.Ext(file_name) == "" {
+			w.log.Warningf("Embedded image %s has no file extension, skipping", src)
+			return
+		}
+
+		// image could be already part of the bundle (e.g. attached to
+		// given item), other images with the same name get numeric suffix
+		source := w.getCanonicalUrl(img_url)
+		for j := 0; j < len(fm.Resources); j++ {
+			if w.isResourceSource(&fm.Resources[j], source) {
+				w.log.Debugf("Embedded image %s already stored as %s", src, fm.Resources[j].Src)
+				result[src] = fm.Resources[j].Src
+				return
+			}
+		}
+
+		attachment_type := w.getAttachmentType(file_name)
+		file_name = w.getUniqueResourceName(fm, attachment_type.Dir, file_name)
+		src_path := path.Join(attachment_type.Dir, file_name)
+		result[src] = src_path
+
+		w.log.Debugf("Processing embedded image %s", src)
+
+		target_dir := filepath.Join(item_dir, attachment_type.Dir)
+		w.ensure_dir(target_dir)
+
+		target_file_path := filepath.Join(target_dir, file_name)
+
+		// fetch file and store it
+		w.downloadFile(img_url.String(), target_file_path)
+
+		alt, _ := img.Attr("alt")
+		r := HugoFrontMatterResource{
+			Src:    src_path,
+			Title:  alt,
+			Params: make(map[string]interface{}),
+			Source: img_url.String(),
+		}
+		r.Params["type"] = attachment_type.Kind
+		r.Params["weight"] = len(fm.Resources)
+		fm.Resources = append(fm.Resources, r)
+	})
+
+	return result
+}
+
+// check if resource was fetched from given (canonical) url
+func (w *WpExport) isResourceSource(r *HugoFrontMatterResource, source string) bool {
+	if r.Source == "" {
+		return false
+	}
+
+	u, err := url.Parse(r.Source)
+	if err != nil {
+		return false
+	}
+
+	return w.getCanonicalUrl(u) == source
+}
+
+// get name of file not used by other resources in given bundle subdirectory,
+// numeric suffix is added to names which are already taken
+func (w *WpExport) getUniqueResourceName(fm *HugoFrontMatter, dir string, file_name string) string {
+
+	used := map[string]bool{}
+	for i := 0; i < len(fm.Resources); i++ {
+		used[path.Clean(filepath.ToSlash(fm.Resources[i].Src))] = true
+	}
+
+	result := file_name
+	ext := path.Ext(file_name)
+	for n := 2; used[path.Join(dir, result)]; n++ {
+		result = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(file_name, ext), n, ext)
+	}
+
+	if result != file_name {
+		w.log.Warningf("Resource %s already exists, storing it as %s", path.Join(dir, file_name), result)
+	}
+
+	return result
+}
+
+// check if host is allowed for downloading of embedded images
+func (w *WpExport) isImageHost(host string) bool {
+	for i := 0; i < len(w.ConfigImageHosts); i++ {
+		if strings.EqualFold(host, w.ConfigImageHosts[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get original path of uploaded media (starting with /wp-content/), empty
+// string is returned for media located elsewhere
+func (w *WpExport) getUploadsPath(media_url string) string {
+	u, err := url.Parse(media_url)
+	if err != nil {
+		return ""
+	}
+
+	pos := strings.Index(u.Path, "/wp-content/")
+	if pos < 0 {
+		return ""
+	}
+
+	return u.Path[pos:]
+}
+
+// make media stored in file_path (published as site_path) available also
+// under its original upload path according to configured backlinks mode
+func (w *WpExport) prepareUploadsBacklink(media_url string, file_path string, site_path string) {
+
+	if w.ConfigUploadsBacklinks == UPLOADS_BACKLINKS_NONE {
+		return
+	}
+
+	uploads_path := w.getUploadsPath(media_url)
+	if uploads_path == "" {
+		w.log.Debugf("Media %s is not located in wp-content, no backlink created", media_url)
+		return
+	}
+
+	if w.ConfigUploadsBacklinks == UPLOADS_BACKLINKS_REDIRECT {
+		w.addRedirect(uploads_path, site_path)
+		return
+	}
+
+	// media file is not available (e.g. downloads are disabled)
+	if _, err := os.Stat(file_path); err != nil {
+		w.log.Debugf("File %s doesn't exist, no backlink created", file_path)
+		return
+	}
+
+	link_path := filepath.Join(w.hugo_static, filepath.FromSlash(uploads_path))
+	if _, err := os.Lstat(link_path); err == nil {
+		w.log.Debugf("File %s exists, keeping existing content (no overwrite)", link_path)
+		return
+	}
+	w.ensure_dir(filepath.Dir(link_path))
+
+	switch w.ConfigUploadsBacklinks {
+	case UPLOADS_BACKLINKS_COPY:
+		w.log.Debugf("Copying %s to %s", file_path, link_path)
+		w.copyFile(file_path, link_path)
+
+	case UPLOADS_BACKLINKS_SYMLINK:
+		target, err := filepath.Rel(filepath.Dir(link_path), file_path)
+		w.check(err)
+		w.log.Debugf("Linking %s to %s", link_path, target)
+		w.check(os.Symlink(target, link_path))
+	}
+}
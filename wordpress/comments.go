@@ -0,0 +1,152 @@
+package wordpress
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// modes of pingbacks and trackbacks export
+const (
+	PINGBACKS_NONE     = "none"
+	PINGBACKS_SEPARATE = "separate"
+	PINGBACKS_INLINE   = "inline"
+)
+
+// wordpress comment statuses and types
+const (
+	COMMENT_APPROVED   = "1"
+	COMMENT_UNAPPROVED = "0"
+	COMMENT_PINGBACK   = "pingback"
+	COMMENT_TRACKBACK  = "trackback"
+)
+
+// modes of exporting urls of comment authors
+const (
+	COMMENT_URLS_NONE     = "none"
+	COMMENT_URLS_NOFOLLOW = "nofollow"
+	COMMENT_URLS_FOLLOW   = "follow"
+)
+
+// rel attribute of untrusted (user generated) links
+const COMMENT_URL_REL = "nofollow ugc"
+
+// split item comments to regular comments and pingbacks (including
+// trackbacks), spam and trashed comments are dropped as well as unapproved
+// ones (unless configured otherwise). Replies to dropped comments are
+// attached to the nearest kept ancestor
+func (w *WpExport) filterComments(item *Item) ([]ItemComment, []ItemComment) {
+
+	var comments []ItemComment
+	var pingbacks []ItemComment
+
+	parents := map[int]int{}
+	kept := map[int]bool{}
+
+	for i := 0; i < len(item.Comments); i++ {
+		c := item.Comments[i]
+		parents[c.Id] = c.ParentId
+
+		// older exports could miss approval status
+		switch c.Approved {
+		case COMMENT_APPROVED, "":
+		case COMMENT_UNAPPROVED:
+			if !w.ConfigUnapprovedComments {
+				w.log.Debugf("Skipping unapproved comment %d of %s (%d)", c.Id, item.Title, item.Id)
+				continue
+			}
+		default:
+			w.log.Debugf("Skipping %s comment %d of %s (%d)", c.Approved, c.Id, item.Title, item.Id)
+			continue
+		}
+
+		w.prepareCommentAuthor(&c)
+		w.prepareCommentContent(&c)
+
+		switch c.Type {
+		case COMMENT_PINGBACK, COMMENT_TRACKBACK:
+			pingbacks = append(pingbacks, c)
+		case "", "comment":
+			c.Type = ""
+			kept[c.Id] = true
+			comments = append(comments, c)
+		default:
+			w.log.Debugf("Skipping comment %d of unknown type %s", c.Id, c.Type)
+		}
+	}
+
+	for i := 0; i < len(comments); i++ {
+		parent_id := comments[i].ParentId
+		for parent_id != 0 && !kept[parent_id] {
+			parent_id = parents[parent_id]
+		}
+		comments[i].ParentId = parent_id
+	}
+
+	// pingbacks are not threaded
+	for i := 0; i < len(pingbacks); i++ {
+		pingbacks[i].ParentId = 0
+	}
+
+	return comments, pingbacks
+}
+
+// derive public author details (gravatar hashes, url) from private ones and
+// scrub email and ip address, so they never get into any output. Authors are
+// replaced by stable pseudonyms in anonymize mode
+func (w *WpExport) prepareCommentAuthor(c *ItemComment) {
+
+	email := strings.ToLower(strings.TrimSpace(c.AuthorEmail))
+
+	c.AuthorEmail = ""
+	c.AuthorIp = ""
+
+	if w.ConfigAnonymizeComments {
+		key := email
+		if key == "" {
+			key = strings.ToLower(strings.TrimSpace(c.Author))
+		}
+		c.Author = getCommentPseudonym(w.ConfigAnonymizeSalt, key)
+		c.AuthorUrl = ""
+		return
+	}
+
+	if email != "" {
+		md5_sum := md5.Sum([]byte(email))
+		c.GravatarMd5 = hex.EncodeToString(md5_sum[:])
+		sha256_sum := sha256.Sum256([]byte(email))
+		c.GravatarSha256 = hex.EncodeToString(sha256_sum[:])
+	}
+
+	c.AuthorUrl = strings.TrimSpace(c.AuthorUrl)
+	if c.AuthorUrl != "" {
+		u, err := url.Parse(c.AuthorUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			w.log.Debugf("Dropping invalid url %s of comment %d author", c.AuthorUrl, c.Id)
+			c.AuthorUrl = ""
+		}
+	}
+
+	switch w.ConfigCommentAuthorUrls {
+	case COMMENT_URLS_NONE:
+		c.AuthorUrl = ""
+	case COMMENT_URLS_NOFOLLOW:
+		if c.AuthorUrl != "" {
+			c.AuthorUrlRel = COMMENT_URL_REL
+		}
+	}
+}
+
+// get pseudonym of comment author, the same key (email or name) always
+// produces the same pseudonym for given salt
+func getCommentPseudonym(salt string, key string) string {
+	if key == "" {
+		return "Anonymous"
+	}
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(key))
+	return "Anonymous " + hex.EncodeToString(mac.Sum(nil))[:8]
+}
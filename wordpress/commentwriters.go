@@ -0,0 +1,558 @@
+package wordpress
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	// isso stores comments in sqlite database, pure go driver keeps build
+	// free of cgo
+	_ "modernc.org/sqlite"
+)
+
+// formats of comments export
+const (
+	COMMENTS_YAML      = "yaml"
+	COMMENTS_DISQUS    = "disqus"
+	COMMENTS_ISSO      = "isso"
+	COMMENTS_REMARK42  = "remark42"
+	COMMENTS_STATICMAN = "staticman"
+)
+
+// placements of comments exported in yaml format
+const (
+	COMMENTS_MODE_BUNDLE      = "bundle"
+	COMMENTS_MODE_BODY        = "body"
+	COMMENTS_MODE_DATA        = "data"
+	COMMENTS_MODE_FRONTMATTER = "frontmatter"
+)
+
+// CommentThread holds threaded comments of single item together with new
+// (hugo) location of the item
+type CommentThread struct {
+	Item *Item
+	Url  string
+	Dir  string
+	// unique key of thread, i.e. content path of item (e.g. posts/2020/hello)
+	Key string
+	// content file of item
+	File string
+
+	Comments  []ItemComment
+	Pingbacks []ItemComment
+}
+
+// CommentWriter exports comments in single format. WriteThread is called for
+// every item with comments, Finish once all items are processed (with all
+// threads), both are optional
+type CommentWriter struct {
+	Name        string
+	WriteThread func(t *CommentThread)
+	Finish      func(threads []CommentThread)
+}
+
+// DefaultCommentWriters returns all supported comment writers keyed by name
+// of format
+func (w *WpExport) DefaultCommentWriters() map[string]CommentWriter {
+	writers := []CommentWriter{
+		{COMMENTS_YAML, w.writeYamlComments, nil},
+		{COMMENTS_DISQUS, nil, w.writeDisqusComments},
+		{COMMENTS_ISSO, nil, w.writeIssoComments},
+		{COMMENTS_REMARK42, nil, w.writeRemark42Comments},
+		{COMMENTS_STATICMAN, w.writeStaticmanComments, nil},
+	}
+
+	result := map[string]CommentWriter{}
+	for i := 0; i < len(writers); i++ {
+		result[writers[i].Name] = writers[i]
+	}
+
+	return result
+}
+
+// check if comments should be exported in given format
+func (w *WpExport) hasCommentsFormat(format string) bool {
+	for i := 0; i < len(w.ConfigCommentsFormats); i++ {
+		if w.ConfigCommentsFormats[i] == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pass comments of item to all configured writers, site wide exports are
+// written at the end of export
+func (w *WpExport) exportCommentThread(t *CommentThread) {
+
+	for i := 0; i < len(w.ConfigCommentsFormats); i++ {
+		writer := w.CommentWriters[w.ConfigCommentsFormats[i]]
+		if writer.WriteThread != nil {
+			writer.WriteThread(t)
+		}
+	}
+
+	w.comment_threads = append(w.comment_threads, *t)
+}
+
+// finish site wide comment exports
+func (w *WpExport) writeCommentThreads() {
+
+	if len(w.comment_threads) == 0 {
+		return
+	}
+
+	for i := 0; i < len(w.ConfigCommentsFormats); i++ {
+		writer := w.CommentWriters[w.ConfigCommentsFormats[i]]
+		if writer.Finish != nil {
+			writer.Finish(w.comment_threads)
+		}
+	}
+}
+
+// get absolute url of site path, path is percent-encoded as it may be
+// decoded original permalink (e.g. with non-ascii slug)
+func (w *WpExport) getAbsoluteUrl(site_path string) string {
+	return strings.TrimSuffix(w.channel.Link, "/") + escapeRedirectPath(site_path)
+}
+
+// get identifier of comment thread used by hosted systems, original
+// permalink is preferred so existing threads are matched
+func (w *WpExport) getThreadIdentifier(t *CommentThread) string {
+	if t.Item.Link != "" {
+		return t.Item.Link
+	}
+	return t.Item.Guid
+}
+
+// call fn for every comment of thread tree (parents before replies)
+func walkComments(comments []ItemComment, parent_id int, fn func(c *ItemComment, parent_id int)) {
+	for i := 0; i < len(comments); i++ {
+		fn(&comments[i], parent_id)
+		walkComments(comments[i].Comments, comments[i].Id, fn)
+	}
+}
+
+////////////// YAML (page bundle, body, data file or front matter)
+
+// comments with pingbacks written to data file
+type dataComments struct {
+	Comments  []ItemComment `json:"comments"`
+	Pingbacks []ItemComment `json:"pingbacks,omitempty"`
+}
+
+func (w *WpExport) writeYamlComments(t *CommentThread) {
+
+	switch w.ConfigCommentsMode {
+	case COMMENTS_MODE_BUNDLE:
+		if len(t.Pingbacks) > 0 {
+			w.writeYaml(t.Pingbacks, filepath.Join(t.Dir, "pingbacks.yaml"))
+		}
+
+		if len(t.Comments) > 0 {
+			file_path := filepath.Join(t.Dir, "comments.yaml")
+			w.log.Debugf("Writing item comments data to file: %s", file_path)
+			w.writeYaml(t.Comments, file_path)
+		}
+
+	case COMMENTS_MODE_BODY:
+		w.log.Debugf("Appending item comments to file: %s", t.File)
+
+		f, err := os.OpenFile(t.File, os.O_APPEND|os.O_WRONLY, 0644)
+		w.check(err)
+
+		// It’s idiomatic to defer a Close immediately after opening a file.
+		defer f.Close()
+
+		w.file_write_str(f, renderCommentsMarkdown(t))
+
+	case COMMENTS_MODE_DATA:
+		// data files are nested by content path, e.g. posts/2020/hello.json
+		file_path := filepath.Join(w.hugo_root, "data", "comments", filepath.FromSlash(t.Key)+".json")
+		w.ensure_dir(filepath.Dir(file_path))
+		w.log.Debugf("Writing item comments data to file: %s", file_path)
+
+		data := dataComments{Comments: t.Comments, Pingbacks: t.Pingbacks}
+		if data.Comments == nil {
+			data.Comments = []ItemComment{}
+		}
+
+		data_bytes, err := json.MarshalIndent(data, "", "  ")
+		w.check(err)
+		w.check(ioutil.WriteFile(file_path, data_bytes, 0644))
+
+	case COMMENTS_MODE_FRONTMATTER:
+		// already part of item front matter
+	}
+}
+
+// render threaded comments (replies as nested quotes) and pingbacks as
+// markdown sections
+func renderCommentsMarkdown(t *CommentThread) string {
+
+	var b strings.Builder
+
+	if len(t.Comments) > 0 {
+		b.WriteString("\n\n## Comments\n")
+
+		var render func(comments []ItemComment, prefix string)
+		render = func(comments []ItemComment, prefix string) {
+			for i := 0; i < len(comments); i++ {
+				c := comments[i]
+
+				author := "**" + renderCommentAuthor(&c) + "**"
+
+				// replies are separated from parent comment by empty line
+				b.WriteString(strings.TrimRight(strings.TrimSuffix(prefix, "> "), " ") + "\n")
+				b.WriteString(prefix + author + " (" + c.Date.Format("2006-01-02") + "):\n")
+				b.WriteString(strings.TrimRight(prefix, " ") + "\n")
+				for _, line := range strings.Split(strings.TrimSpace(c.Content), "\n") {
+					b.WriteString(strings.TrimRight(prefix+line, " ") + "\n")
+				}
+
+				render(c.Comments, prefix+"> ")
+			}
+		}
+		render(t.Comments, "")
+	}
+
+	if len(t.Pingbacks) > 0 {
+		b.WriteString("\n## Pingbacks\n\n")
+		for i := 0; i < len(t.Pingbacks); i++ {
+			b.WriteString("* " + renderCommentAuthor(&t.Pingbacks[i]) + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// render escaped author name as markdown link to author url, markdown links
+// can't be marked by rel attribute, so untrusted urls are dropped
+func renderCommentAuthor(c *ItemComment) string {
+	name := markdown_text_escapes.Replace(c.Author)
+	if c.AuthorUrl == "" || c.AuthorUrlRel != "" {
+		return name
+	}
+
+	return "[" + name + "](" + markdown_url_escapes.Replace(c.AuthorUrl) + ")"
+}
+
+////////////// Disqus import (WXR)
+
+type disqusRss struct {
+	XMLName      xml.Name     `xml:"rss"`
+	Version      string       `xml:"version,attr"`
+	XmlnsContent string       `xml:"xmlns:content,attr"`
+	XmlnsDsq     string       `xml:"xmlns:dsq,attr"`
+	XmlnsDc      string       `xml:"xmlns:dc,attr"`
+	XmlnsWp      string       `xml:"xmlns:wp,attr"`
+	Items        []disqusItem `xml:"channel>item"`
+}
+
+type disqusCdata struct {
+	Text string `xml:",cdata"`
+}
+
+type disqusItem struct {
+	Title            string          `xml:"title"`
+	Link             string          `xml:"link"`
+	Content          disqusCdata     `xml:"content:encoded"`
+	ThreadIdentifier string          `xml:"dsq:thread_identifier"`
+	PostDateGmt      string          `xml:"wp:post_date_gmt"`
+	CommentStatus    string          `xml:"wp:comment_status"`
+	Comments         []disqusComment `xml:"wp:comment"`
+}
+
+type disqusComment struct {
+	Id       int         `xml:"wp:comment_id"`
+	Author   string      `xml:"wp:comment_author"`
+	Url      string      `xml:"wp:comment_author_url,omitempty"`
+	DateGmt  string      `xml:"wp:comment_date_gmt"`
+	Content  disqusCdata `xml:"wp:comment_content"`
+	Approved int         `xml:"wp:comment_approved"`
+	ParentId int         `xml:"wp:comment_parent"`
+}
+
+// write comments in disqus import format (wordpress WXR), threads are
+// identified by original permalinks and linked to new hugo urls
+func (w *WpExport) writeDisqusComments(threads []CommentThread) {
+
+	file_path := filepath.Join(w.hugo_root, "comments.disqus.xml")
+	w.log.Infof("Writing disqus comments (%d threads) to file: %s", len(threads), file_path)
+
+	rss := disqusRss{
+		Version:      "2.0",
+		XmlnsContent: "http://purl.org/rss/1.0/modules/content/",
+		XmlnsDsq:     "http://www.disqus.com/",
+		XmlnsDc:      "http://purl.org/dc/elements/1.1/",
+		XmlnsWp:      "http://wordpress.org/export/1.0/",
+	}
+
+	const date_format = "2006-01-02 15:04:05"
+
+	for i := 0; i < len(threads); i++ {
+		t := &threads[i]
+
+		if len(t.Comments) == 0 {
+			continue
+		}
+
+		item := disqusItem{
+			Title:            t.Item.Title,
+			Link:             w.getAbsoluteUrl(t.Url),
+			ThreadIdentifier: w.getThreadIdentifier(t),
+			PostDateGmt:      t.Item.GetDateGmt().Format(date_format),
+			CommentStatus:    "open",
+		}
+
+		walkComments(t.Comments, 0, func(c *ItemComment, parent_id int) {
+			item.Comments = append(item.Comments, disqusComment{
+				Id:       c.Id,
+				Author:   c.Author,
+				Url:      c.AuthorUrl,
+				DateGmt:  c.GetDateGmt().Format(date_format),
+				Content:  disqusCdata{c.ContentHtml},
+				Approved: 1,
+				ParentId: parent_id,
+			})
+		})
+
+		rss.Items = append(rss.Items, item)
+	}
+
+	f, err := os.Create(file_path)
+	w.check(err)
+
+	// It’s idiomatic to defer a Close immediately after opening a file.
+	defer f.Close()
+
+	w.file_write_str(f, xml.Header)
+
+	encoder := xml.NewEncoder(f)
+	encoder.Indent("", "  ")
+	w.check(encoder.Encode(rss))
+}
+
+////////////// Isso (sqlite database)
+
+// schema of isso database (version 3)
+var isso_schema = []string{
+	"CREATE TABLE preferences (key VARCHAR PRIMARY KEY, value VARCHAR)",
+	"CREATE TABLE threads (id INTEGER PRIMARY KEY, uri VARCHAR(256) UNIQUE, title VARCHAR(256))",
+	"CREATE TABLE comments (tid REFERENCES threads(id), id INTEGER PRIMARY KEY, parent INTEGER, " +
+		"created FLOAT NOT NULL, modified FLOAT, mode INTEGER, remote_addr VARCHAR, text VARCHAR, " +
+		"author VARCHAR, email VARCHAR, website VARCHAR, likes INTEGER DEFAULT 0, dislikes INTEGER DEFAULT 0, " +
+		"voters BLOB NOT NULL, notification INTEGER DEFAULT 0)",
+	"PRAGMA user_version = 3",
+}
+
+// isso comment mode of accepted (public) comments
+const ISSO_MODE_ACCEPTED = 1
+
+// write comments to new isso sqlite database, threads are identified by new
+// hugo urls (isso uses location path of page as sent by browser, i.e.
+// percent-encoded)
+func (w *WpExport) writeIssoComments(threads []CommentThread) {
+
+	file_path := filepath.Join(w.hugo_root, "comments.isso.db")
+	w.log.Infof("Writing isso comments (%d threads) to database: %s", len(threads), file_path)
+
+	// database is always created from scratch
+	if _, err := os.Stat(file_path); err == nil {
+		w.check(os.Remove(file_path))
+	}
+
+	db, err := sql.Open("sqlite", file_path)
+	w.check(err)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	w.check(err)
+
+	for i := 0; i < len(isso_schema); i++ {
+		_, err = tx.Exec(isso_schema[i])
+		w.check(err)
+	}
+
+	// empty bloom filter of voters
+	voters := make([]byte, 256)
+
+	for i := 0; i < len(threads); i++ {
+		t := &threads[i]
+
+		if len(t.Comments) == 0 {
+			continue
+		}
+
+		res, err := tx.Exec("INSERT INTO threads (uri, title) VALUES (?, ?)", escapeRedirectPath(t.Url), t.Item.Title)
+		w.check(err)
+		tid, err := res.LastInsertId()
+		w.check(err)
+
+		walkComments(t.Comments, 0, func(c *ItemComment, parent_id int) {
+			var parent interface{}
+			if parent_id != 0 {
+				parent = parent_id
+			}
+
+			var website interface{}
+			if c.AuthorUrl != "" {
+				website = c.AuthorUrl
+			}
+
+			created := float64(c.GetDateGmt().Unix())
+			_, err := tx.Exec("INSERT INTO comments (tid, id, parent, created, modified, mode, remote_addr, "+
+				"text, author, email, website, voters) VALUES (?, ?, ?, ?, NULL, ?, '', ?, ?, NULL, ?, ?)",
+				tid, c.Id, parent, created, ISSO_MODE_ACCEPTED, c.Content, c.Author, website, voters)
+			w.check(err)
+		})
+	}
+
+	w.check(tx.Commit())
+}
+
+////////////// Remark42 (backup)
+
+type remark42Header struct {
+	Version int           `json:"version"`
+	Users   []interface{} `json:"users"`
+	Posts   []interface{} `json:"posts"`
+}
+
+type remark42User struct {
+	Name    string `json:"name"`
+	Id      string `json:"id"`
+	Picture string `json:"picture"`
+	Ip      string `json:"ip"`
+	Admin   bool   `json:"admin"`
+}
+
+type remark42Locator struct {
+	Site string `json:"site"`
+	Url  string `json:"url"`
+}
+
+type remark42Comment struct {
+	Id       string          `json:"id"`
+	ParentId string          `json:"pid"`
+	Text     string          `json:"text"`
+	User     remark42User    `json:"user"`
+	Locator  remark42Locator `json:"locator"`
+	Score    int             `json:"score"`
+	Vote     int             `json:"vote"`
+	Time     string          `json:"time"`
+	Title    string          `json:"title"`
+}
+
+// write comments as remark42 backup (json lines with header), which could be
+// restored by `remark42 restore`. Threads are located by new hugo urls
+func (w *WpExport) writeRemark42Comments(threads []CommentThread) {
+
+	file_path := filepath.Join(w.hugo_root, "comments.remark42.json")
+	w.log.Infof("Writing remark42 comments (%d threads) to file: %s", len(threads), file_path)
+
+	f, err := os.Create(file_path)
+	w.check(err)
+
+	// It’s idiomatic to defer a Close immediately after opening a file.
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetEscapeHTML(false)
+
+	w.check(encoder.Encode(remark42Header{Version: 1, Users: []interface{}{}, Posts: []interface{}{}}))
+
+	for i := 0; i < len(threads); i++ {
+		t := &threads[i]
+
+		walkComments(t.Comments, 0, func(c *ItemComment, parent_id int) {
+			comment := remark42Comment{
+				Id:   "wp-" + strconv.Itoa(c.Id),
+				Text: c.ContentHtml,
+				User: remark42User{
+					Name:    c.Author,
+					Id:      "wordpress_" + remark42UserHash(c.Author),
+					Picture: getGravatarUrl(c),
+				},
+				Locator: remark42Locator{
+					Site: w.ConfigRemark42Site,
+					Url:  w.getAbsoluteUrl(t.Url),
+				},
+				Time:  c.GetDateGmt().Format("2006-01-02T15:04:05Z"),
+				Title: t.Item.Title,
+			}
+			if parent_id != 0 {
+				comment.ParentId = "wp-" + strconv.Itoa(parent_id)
+			}
+
+			w.check(encoder.Encode(comment))
+		})
+	}
+}
+
+// get url of gravatar image of comment author, empty string if author has no
+// gravatar hash
+func getGravatarUrl(c *ItemComment) string {
+	if c.GravatarMd5 == "" {
+		return ""
+	}
+	return "https://www.gravatar.com/avatar/" + c.GravatarMd5
+}
+
+// stable user id derived from author name (wordpress has no user ids for
+// anonymous commenters)
+func remark42UserHash(name string) string {
+	h := sha1.Sum([]byte(name))
+	return hex.EncodeToString(h[:])
+}
+
+////////////// Staticman (data files)
+
+type staticmanComment struct {
+	Id       string `yaml:"_id"`
+	ParentId string `yaml:"replying_to,omitempty"`
+	Name     string `yaml:"name"`
+	Email    string `yaml:"email,omitempty"`
+	Url      string `yaml:"url,omitempty"`
+	Date     int64  `yaml:"date"`
+	Message  string `yaml:"message"`
+}
+
+// write every comment to its own file in data/comments/<key>/, as staticman
+// does for newly submitted comments
+func (w *WpExport) writeStaticmanComments(t *CommentThread) {
+
+	if len(t.Comments) == 0 {
+		return
+	}
+
+	dir := filepath.Join(w.hugo_root, "data", "comments", filepath.FromSlash(t.Key))
+	w.ensure_dir(dir)
+
+	w.log.Debugf("Writing staticman comments of %s (%d) to dir: %s", t.Item.Title, t.Item.Id, dir)
+
+	walkComments(t.Comments, 0, func(c *ItemComment, parent_id int) {
+		comment := staticmanComment{
+			Id:      strconv.Itoa(c.Id),
+			Name:    c.Author,
+			Email:   c.GravatarMd5,
+			Url:     c.AuthorUrl,
+			Date:    c.GetDateGmt().Unix(),
+			Message: c.Content,
+		}
+		if parent_id != 0 {
+			comment.ParentId = strconv.Itoa(parent_id)
+		}
+
+		file_name := fmt.Sprintf("comment-%d-%d.yml", c.GetDateGmt().Unix(), c.Id)
+		w.writeYaml(comment, filepath.Join(dir, file_name))
+	})
+}
@@ -0,0 +1,404 @@
+package wordpress
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// collect hosts considered as this site: host of channel link, its variant
+// with or without www prefix and configured aliases
+func (w *WpExport) prepareSiteHosts() {
+
+	w.site_hosts = map[string]bool{}
+
+	site_url, err := url.Parse(w.channel.Link)
+	w.check(err)
+
+	host := strings.ToLower(site_url.Host)
+	w.site_hosts[host] = true
+	if strings.HasPrefix(host, "www.") {
+		w.site_hosts[strings.TrimPrefix(host, "www.")] = true
+	} else {
+		w.site_hosts["www."+host] = true
+	}
+
+	for i := 0; i < len(w.ConfigSiteAliases); i++ {
+		alias := strings.ToLower(strings.TrimSpace(w.ConfigSiteAliases[i]))
+
+		// aliases could be specified as urls or hosts
+		if u, err := url.Parse(alias); err == nil && u.Host != "" {
+			alias = u.Host
+		}
+
+		w.site_hosts[alias] = true
+	}
+
+	w.log.Debugf("Site hosts: %v", w.site_hosts)
+}
+
+// check if host belongs to this site
+func (w *WpExport) isSiteHost(host string) bool {
+	if w.site_hosts == nil {
+		w.prepareSiteHosts()
+	}
+
+	return w.site_hosts[strings.ToLower(host)]
+}
+
+// get url with scheme and host of channel link for urls pointing to this
+// site (on any of its hosts), other urls are returned unchanged
+func (w *WpExport) getCanonicalUrl(u *url.URL) string {
+	if !w.isSiteHost(u.Host) {
+		return u.String()
+	}
+
+	site_url, err := url.Parse(w.channel.Link)
+	w.check(err)
+
+	result := *u
+	result.Scheme = site_url.Scheme
+	result.Host = site_url.Host
+
+	return result.String()
+}
+
+// build index of all known urls of exported items (permalinks, guids and
+// id based urls) mapped to hugo content paths usable in ref shortcode
+func (w *WpExport) buildLinkIndex() {
+
+	w.link_index = map[string]string{}
+
+	ch := w.channel
+	for i := 0; i < len(ch.Items); i++ {
+		item := &ch.Items[i]
+
+		var target *Item
+		switch {
+		case w.IsExported(item):
+			target = item
+		case item.Type == "attachment" && item.ParentId != 0:
+			// attachment pages are resolved to their parent items
+			target = w.FindItem(item.ParentId)
+			if target == nil || !w.IsExported(target) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		rel_dir, err := filepath.Rel(w.hugo_content, w.getItemDir(target))
+		w.check(err)
+		ref := "/" + filepath.ToSlash(rel_dir)
+
+		id := strconv.Itoa(item.Id)
+		keys := []string{item.Link, item.Guid}
+		if item.Type == "attachment" {
+			keys = append(keys, "?attachment_id="+id)
+		} else {
+			keys = append(keys, "?p="+id, "?page_id="+id)
+		}
+
+		for j := 0; j < len(keys); j++ {
+			if key, ok := w.getLinkKey(keys[j]); ok {
+				w.link_index[key] = ref
+			}
+		}
+	}
+
+	w.log.Infof("Link index contains %d urls", len(w.link_index))
+}
+
+// get normalized form of link to this site used as a key in link index,
+// second return value is false for links to other sites
+func (w *WpExport) getLinkKey(link string) (string, bool) {
+
+	if link == "" {
+		return "", false
+	}
+
+	site_url, err := url.Parse(w.channel.Link)
+	w.check(err)
+
+	u, err := site_url.Parse(link)
+	if err != nil || !w.isSiteHost(u.Host) {
+		return "", false
+	}
+
+	// id based links, path is irrelevant
+	q := u.Query()
+	for _, param := range []string{"p", "page_id", "attachment_id"} {
+		if id := q.Get(param); id != "" {
+			return "?" + param + "=" + id, true
+		}
+	}
+
+	key := strings.TrimSuffix(u.Path, "/")
+	if key == "" {
+		key = "/"
+	}
+
+	return key, true
+}
+
+// resolve link to this site to hugo content path of exported item
+func (w *WpExport) resolveInternalLink(link string) (string, bool) {
+
+	key, ok := w.getLinkKey(link)
+	if !ok {
+		return "", false
+	}
+
+	ref, ok := w.link_index[key]
+	if !ok {
+		return "", false
+	}
+
+	// keep anchors
+	if pos := strings.Index(link, "#"); pos >= 0 {
+		ref += link[pos:]
+	}
+
+	return ref, true
+}
+
+// attribute used to mark elements with urls rewritten to bundle resources
+const LINK_RESOURCE_ATTR = "data-wp2hugo-resource"
+
+// LinkContext describes url found in item content
+type LinkContext struct {
+	Item        *Item
+	FrontMatter *HugoFrontMatter
+	ItemMedia   map[string]string
+
+	// element containing url and name of attribute holding it
+	Selection *goquery.Selection
+	Attr      string
+
+	// original value of attribute and absolute url derived from it
+	Raw string
+	Url *url.URL
+
+	// url points to this site
+	Internal bool
+}
+
+// LinkRewriter rewrites url found in item content, new url and true is
+// returned if url was handled (no other rewriters are called then)
+type LinkRewriter struct {
+	Name    string
+	Rewrite func(ctx *LinkContext) (string, bool)
+}
+
+// DefaultLinkRewriters returns pipeline of rewriters in order of application
+func (w *WpExport) DefaultLinkRewriters() []LinkRewriter {
+	return []LinkRewriter{
+		{"static", w.rewriteStaticMediaLink},
+		{"embedded", w.rewriteEmbeddedMediaLink},
+		{"images", w.rewriteImageLink},
+		{"media", w.rewriteMediaLink},
+		{"archives", w.rewriteArchiveLink},
+		{"items", w.rewriteItemLink},
+	}
+}
+
+// rewrite urls of links, images and other media in item content (html)
+func (w *WpExport) rewriteLinks(item *Item, fm *HugoFrontMatter, item_media map[string]string, doc *goquery.Selection) {
+
+	site_url, err := url.Parse(w.channel.Link)
+	w.check(err)
+
+	attrs := []struct {
+		selector string
+		attr     string
+	}{
+		{"a[href]", "href"},
+		{"img[src]", "src"},
+		{"audio[src], video[src], source[src], embed[src]", "src"},
+	}
+
+	for i := 0; i < len(attrs); i++ {
+		attr := attrs[i].attr
+		doc.Find(attrs[i].selector).Each(func(j int, s *goquery.Selection) {
+			raw := strings.TrimSpace(s.AttrOr(attr, ""))
+			if raw == "" || strings.HasPrefix(raw, "#") {
+				return
+			}
+
+			u, err := site_url.Parse(raw)
+			if err != nil {
+				w.log.Warningf("Invalid url %s in %s (%d)", raw, item.Title, item.Id)
+				return
+			}
+
+			ctx := LinkContext{
+				Item:        item,
+				FrontMatter: fm,
+				ItemMedia:   item_media,
+				Selection:   s,
+				Attr:        attr,
+				Raw:         raw,
+				Url:         u,
+				Internal:    w.isSiteHost(u.Host),
+			}
+
+			for k := 0; k < len(w.LinkRewriters); k++ {
+				if new_url, ok := w.LinkRewriters[k].Rewrite(&ctx); ok {
+					w.log.Debugf("Link %s rewritten by %s rewriter to %s", raw, w.LinkRewriters[k].Name, new_url)
+					s.SetAttr(attr, new_url)
+					return
+				}
+			}
+		})
+	}
+}
+
+// media exported to static dir are referenced by their site paths
+func (w *WpExport) rewriteStaticMediaLink(ctx *LinkContext) (string, bool) {
+	site_path, ok := w.static_media[w.getCanonicalUrl(ctx.Url)]
+	return site_path, ok
+}
+
+// media downloaded to item bundle are referenced by bundle paths
+func (w *WpExport) rewriteEmbeddedMediaLink(ctx *LinkContext) (string, bool) {
+	bundle_path, ok := ctx.ItemMedia[ctx.Raw]
+	if ok {
+		ctx.Selection.SetAttr(LINK_RESOURCE_ATTR, "image")
+	}
+	return bundle_path, ok
+}
+
+// images (both embedded and linked) attached to item are referenced by
+// bundle paths
+func (w *WpExport) rewriteImageLink(ctx *LinkContext) (string, bool) {
+	r := w.findLinkResource(ctx)
+	if r == nil || r.Params["type"] != "image" {
+		return "", false
+	}
+
+	ctx.Selection.SetAttr(LINK_RESOURCE_ATTR, "image")
+
+	return r.Src, true
+}
+
+// other media attached to item are referenced by bundle paths
+func (w *WpExport) rewriteMediaLink(ctx *LinkContext) (string, bool) {
+	r := w.findLinkResource(ctx)
+	if r == nil {
+		if ctx.Internal && strings.Contains(ctx.Url.Path, "/wp-content/") {
+			w.log.Warningf("Media %s is not part of %s (%d), keeping original url", ctx.Raw, ctx.Item.Title, ctx.Item.Id)
+		}
+		return "", false
+	}
+
+	ctx.Selection.SetAttr(LINK_RESOURCE_ATTR, fmt.Sprint(r.Params["type"]))
+
+	return r.Src, true
+}
+
+// look for item resource matching url of media uploaded to this site
+func (w *WpExport) findLinkResource(ctx *LinkContext) *HugoFrontMatterResource {
+	if !ctx.Internal {
+		return nil
+	}
+
+	return w.findUploadResource(ctx.FrontMatter, ctx.Url)
+}
+
+// look for item resource matching uploaded media url, resized variants of
+// images (e.g. photo-300x200.jpg) are matched to original images
+func (w *WpExport) findUploadResource(fm *HugoFrontMatter, u *url.URL) *HugoFrontMatterResource {
+	if !strings.Contains(u.Path, "/wp-content/") {
+		return nil
+	}
+
+	file_name := strings.ToLower(path.Base(u.Path))
+	file_ext := path.Ext(file_name)
+	candidates := []string{
+		file_name,
+		link_image_size.ReplaceAllString(strings.TrimSuffix(file_name, file_ext), "") + file_ext,
+	}
+
+	for i := 0; i < len(candidates); i++ {
+		for j := 0; j < len(fm.Resources); j++ {
+			if path.Base(fm.Resources[j].Src) != candidates[i] {
+				continue
+			}
+			// file with the same name uploaded elsewhere is another media
+			if source, err := url.Parse(fm.Resources[j].Source); err == nil && fm.Resources[j].Source != "" {
+				if path.Dir(source.Path) != path.Dir(u.Path) {
+					continue
+				}
+			}
+			return &fm.Resources[j]
+		}
+	}
+
+	return nil
+}
+
+// suffixes added by wordpress to names of resized images
+var link_image_size = regexp.MustCompile(`-(\d+x\d+|scaled)$`)
+
+// links to posts and pages are referenced by ref shortcode
+func (w *WpExport) rewriteItemLink(ctx *LinkContext) (string, bool) {
+	if !ctx.Internal || ctx.Selection.Is("img") {
+		return "", false
+	}
+
+	ref, ok := w.resolveInternalLink(ctx.Url.String())
+	if !ok {
+		w.log.Warningf("Unable to resolve internal link %s in %s (%d), keeping original url", ctx.Raw, ctx.Item.Title, ctx.Item.Id)
+		return "", false
+	}
+
+	return `{{<ref "` + ref + `" >}}`, true
+}
+
+// create html to markdown converter, images pointing to bundle resources
+// are rendered as figure shortcodes
+func (w *WpExport) newConverter() *md.Converter {
+	converter := md.NewConverter("", true, nil)
+
+	converter.AddRules(
+		md.Rule{
+			Filter: []string{"img"},
+			Replacement: func(content string, s *goquery.Selection, opt *md.Options) *string {
+				if s.AttrOr(LINK_RESOURCE_ATTR, "") != "image" {
+					return nil
+				}
+
+				figure := `{{<figure src="` + s.AttrOr("src", "") + `"`
+				if alt := strings.TrimSpace(s.AttrOr("alt", "")); alt != "" {
+					figure += ` alt="` + strings.ReplaceAll(alt, `"`, `\"`) + `"`
+				}
+				figure += `>}}`
+
+				return &figure
+			},
+		},
+		md.Rule{
+			// image linked to bundle resource (typically to its own full
+			// size version) is rendered as figure only
+			Filter: []string{"a"},
+			Replacement: func(content string, s *goquery.Selection, opt *md.Options) *string {
+				children := s.Children()
+				if s.AttrOr(LINK_RESOURCE_ATTR, "") == "" || children.Length() != 1 ||
+					children.AttrOr(LINK_RESOURCE_ATTR, "") != "image" || strings.TrimSpace(s.Text()) != "" {
+					return nil
+				}
+
+				return &content
+			},
+		},
+	)
+
+	return converter
+}
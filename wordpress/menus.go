@@ -0,0 +1,200 @@
+package wordpress
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// post type of navigation menu items
+const MENU_ITEM_TYPE = "nav_menu_item"
+
+// types of navigation menu items (meta _menu_item_type)
+const (
+	MENU_ITEM_POST_TYPE = "post_type"
+	MENU_ITEM_TAXONOMY  = "taxonomy"
+	MENU_ITEM_CUSTOM    = "custom"
+)
+
+// HugoMenuEntry is single entry of hugo menu, internal targets are referenced
+// by pageRef, others by url
+type HugoMenuEntry struct {
+	Identifier string `yaml:"identifier"`
+	Name       string `yaml:"name"`
+	PageRef    string `yaml:"pageRef,omitempty"`
+	Url        string `yaml:"url,omitempty"`
+	Parent     string `yaml:"parent,omitempty"`
+	Weight     int    `yaml:"weight"`
+}
+
+// get value of item meta, second return value is false if meta is missing
+func (item *Item) GetMeta(key string) (string, bool) {
+	for i := 0; i < len(item.Meta); i++ {
+		if item.Meta[i].Key == key {
+			return item.Meta[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// convert wordpress navigation menus to hugo menus keyed by menu slug,
+// entries keep nesting (parent identifiers) and order (weights)
+func (w *WpExport) getMenus() map[string][]HugoMenuEntry {
+
+	var items []*Item
+	ch := w.channel
+	for i := 0; i < len(ch.Items); i++ {
+		if ch.Items[i].Type == MENU_ITEM_TYPE {
+			items = append(items, &ch.Items[i])
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].MenuOrder < items[j].MenuOrder
+	})
+
+	menus := map[string][]HugoMenuEntry{}
+
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		var menu string
+		for j := 0; j < len(item.Categories); j++ {
+			if item.Categories[j].Domain == TAXONOMY_NAV_MENU {
+				menu = item.Categories[j].Slug()
+				break
+			}
+		}
+		if menu == "" {
+			w.log.Warningf("Menu item %s (%d) is not part of any menu, skipping", item.Title, item.Id)
+			continue
+		}
+
+		entry, ok := w.getMenuEntry(item)
+		if !ok {
+			continue
+		}
+
+		menus[menu] = append(menus[menu], entry)
+	}
+
+	for menu, entries := range menus {
+		w.log.Infof("Menu %s contains %d entries", menu, len(entries))
+	}
+
+	return menus
+}
+
+func (w *WpExport) getMenuEntry(item *Item) (HugoMenuEntry, bool) {
+
+	entry := HugoMenuEntry{
+		Identifier: "menu-item-" + strconv.Itoa(item.Id),
+		Name:       item.Title,
+		Weight:     item.MenuOrder,
+	}
+
+	if parent, _ := item.GetMeta("_menu_item_menu_item_parent"); parent != "" && parent != "0" {
+		entry.Parent = "menu-item-" + parent
+	}
+
+	menu_type, _ := item.GetMeta("_menu_item_type")
+	object, _ := item.GetMeta("_menu_item_object")
+	object_id_value, _ := item.GetMeta("_menu_item_object_id")
+	object_id, _ := strconv.Atoi(object_id_value)
+
+	switch menu_type {
+	case MENU_ITEM_POST_TYPE:
+		target := w.FindItem(object_id)
+		if target == nil {
+			w.log.Warningf("Target %d of menu item %s (%d) not found, skipping", object_id, item.Title, item.Id)
+			return entry, false
+		}
+		if entry.Name == "" {
+			entry.Name = target.Title
+		}
+
+		if ref, ok := w.resolveInternalLink("?p=" + object_id_value); ok {
+			entry.PageRef = ref
+		} else {
+			w.log.Warningf("Target %s (%d) of menu item %d is not exported, keeping original url", target.Title, target.Id, item.Id)
+			entry.Url = target.Link
+		}
+
+	case MENU_ITEM_TAXONOMY:
+		slug, title, ok := w.findTerm(object, object_id)
+		if !ok {
+			w.log.Warningf("Term %d (%s) of menu item %s (%d) not found, skipping", object_id, object, item.Title, item.Id)
+			return entry, false
+		}
+		if entry.Name == "" {
+			entry.Name = title
+		}
+		entry.PageRef = "/" + w.getTaxonomyName(object) + "/" + slug
+
+	default:
+		link, _ := item.GetMeta("_menu_item_url")
+		if ref, ok := w.resolveInternalLink(link); ok && !strings.Contains(ref, "#") {
+			entry.PageRef = ref
+		} else {
+			entry.Url = w.getMenuUrl(link)
+		}
+	}
+
+	if entry.Url == "" && entry.PageRef == "" {
+		w.log.Warningf("Menu item %s (%d) has no target, skipping", item.Title, item.Id)
+		return entry, false
+	}
+
+	return entry, true
+}
+
+// get hugo url of custom menu link, links to this site are resolved to
+// archives, other links are kept
+func (w *WpExport) getMenuUrl(link string) string {
+
+	if _, ok := w.getLinkKey(link); !ok {
+		return link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	if target, ok := w.getArchiveTarget(u.Path); ok {
+		return target
+	}
+
+	w.log.Warningf("Unable to resolve menu link %s, keeping original url", link)
+
+	return link
+}
+
+// find slug and title of term by its taxonomy (domain) and id
+func (w *WpExport) findTerm(taxonomy string, id int) (string, string, bool) {
+
+	ch := w.channel
+
+	switch taxonomy {
+	case "category":
+		for i := 0; i < len(ch.Categories); i++ {
+			if ch.Categories[i].Id == id {
+				return TermSlug(ch.Categories[i].Name, ch.Categories[i].Title), ch.Categories[i].Title, true
+			}
+		}
+	case "post_tag":
+		for i := 0; i < len(ch.Tags); i++ {
+			if ch.Tags[i].Id == id {
+				return TermSlug(ch.Tags[i].Name, ch.Tags[i].Title), ch.Tags[i].Title, true
+			}
+		}
+	default:
+		for i := 0; i < len(ch.Terms); i++ {
+			if ch.Terms[i].Taxonomy == taxonomy && ch.Terms[i].Id == id {
+				return TermSlug(ch.Terms[i].Name, ch.Terms[i].Title), ch.Terms[i].Title, true
+			}
+		}
+	}
+
+	return "", "", false
+}
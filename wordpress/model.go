@@ -2,7 +2,8 @@ package wordpress
 
 import (
 	"encoding/xml"
-	"fmt"
+	"net/url"
+	"time"
 )
 
 ////////////// FrontMatter
@@ -11,16 +12,30 @@ type HugoFrontMatter struct {
 	Title         string                    `yaml:"title"`
 	Date          string                    `yaml:"date"`
 	Slug          string                    `yaml:"slug,omitempty"`
+	Url           string                    `yaml:"url,omitempty"`
+	Weight        int                       `yaml:"weight,omitempty"`
+	Layout        string                    `yaml:"layout,omitempty"`
+	Type          string                    `yaml:"type,omitempty"`
+	Aliases       []string                  `yaml:"aliases,omitempty"`
 	FeaturedImage string                    `yaml:"featured_image,omitempty"`
-	Categories    []string                  `yaml:"categories,omitempty"`
-	Tags          []string                  `yaml:"tags,omitempty"`
+	Taxonomies    map[string][]string       `yaml:",inline"`
 	Resources     []HugoFrontMatterResource `yaml:"resources,omitempty"`
+	Comments      []ItemComment             `yaml:"comments,omitempty"`
+	Pingbacks     []ItemComment             `yaml:"pingbacks,omitempty"`
+}
+
+type HugoTermFrontMatter struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description,omitempty"`
+	Parent      string `yaml:"parent,omitempty"`
 }
 
 type HugoFrontMatterResource struct {
 	Src    string                 `yaml:"src"`
 	Title  string                 `yaml:"title"`
 	Params map[string]interface{} `yaml:"params,omitempty"`
+	// original url of resource, used to tell apart files with the same name
+	Source string `yaml:"-"`
 }
 
 ////////////// WP XML
@@ -35,7 +50,43 @@ type Channel struct {
 	Title       string   `xml:"title"`
 	Description string   `xml:"description"`
 	Link        string   `xml:"link"`
+	Language    string   `xml:"language"`
 	Items       []Item   `xml:"item"`
+
+	Categories []ChannelCategory `xml:"http://wordpress.org/export/1.2/ category"`
+	Tags       []ChannelTag      `xml:"http://wordpress.org/export/1.2/ tag"`
+	Terms      []ChannelTerm     `xml:"http://wordpress.org/export/1.2/ term"`
+	Authors    []ChannelAuthor   `xml:"http://wordpress.org/export/1.2/ author"`
+}
+
+type ChannelCategory struct {
+	Id          int    `xml:"http://wordpress.org/export/1.2/ term_id"`
+	Name        string `xml:"http://wordpress.org/export/1.2/ category_nicename"`
+	Parent      string `xml:"http://wordpress.org/export/1.2/ category_parent"`
+	Title       string `xml:"http://wordpress.org/export/1.2/ cat_name"`
+	Description string `xml:"http://wordpress.org/export/1.2/ category_description"`
+}
+
+type ChannelTerm struct {
+	Id          int    `xml:"http://wordpress.org/export/1.2/ term_id"`
+	Taxonomy    string `xml:"http://wordpress.org/export/1.2/ term_taxonomy"`
+	Name        string `xml:"http://wordpress.org/export/1.2/ term_slug"`
+	Parent      string `xml:"http://wordpress.org/export/1.2/ term_parent"`
+	Title       string `xml:"http://wordpress.org/export/1.2/ term_name"`
+	Description string `xml:"http://wordpress.org/export/1.2/ term_description"`
+}
+
+type ChannelTag struct {
+	Id          int    `xml:"http://wordpress.org/export/1.2/ term_id"`
+	Name        string `xml:"http://wordpress.org/export/1.2/ tag_slug"`
+	Title       string `xml:"http://wordpress.org/export/1.2/ tag_name"`
+	Description string `xml:"http://wordpress.org/export/1.2/ tag_description"`
+}
+
+type ChannelAuthor struct {
+	Id          int    `xml:"http://wordpress.org/export/1.2/ author_id"`
+	Login       string `xml:"http://wordpress.org/export/1.2/ author_login"`
+	DisplayName string `xml:"http://wordpress.org/export/1.2/ author_display_name"`
 }
 
 type Item struct {
@@ -44,11 +95,14 @@ type Item struct {
 	Name          string         `xml:"http://wordpress.org/export/1.2/ post_name"`
 	ParentId      int            `xml:"http://wordpress.org/export/1.2/ post_parent"`
 	Title         string         `xml:"title"`
+	Link          string         `xml:"link"`
+	Guid          string         `xml:"guid"`
 	Creator       string         `xml:"http://purl.org/dc/elements/1.1/ creator"`
 	Content       string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 	Type          string         `xml:"http://wordpress.org/export/1.2/ post_type"`
 	MenuOrder     int            `xml:"http://wordpress.org/export/1.2/ menu_order"`
 	PostDate      wp_date        `xml:"http://wordpress.org/export/1.2/ post_date"`
+	PostDateGmt   wp_date        `xml:"http://wordpress.org/export/1.2/ post_date_gmt"`
 	Categories    []ItemCategory `xml:"category"`
 	AttachmentUrl string         `xml:"http://wordpress.org/export/1.2/ attachment_url"`
 	Meta          []ItemMeta     `xml:"http://wordpress.org/export/1.2/ postmeta"`
@@ -57,6 +111,12 @@ type Item struct {
 	Attachments []Item
 }
 
+// get publication date in UTC, local date is used for items without gmt date
+// (e.g. drafts)
+func (item *Item) GetDateGmt() time.Time {
+	return getGmtDate(item.PostDate, item.PostDateGmt)
+}
+
 type ItemCategory struct {
 	XMLName xml.Name `xml:"category"`
 	Domain  string   `xml:"domain,attr"`
@@ -64,35 +124,73 @@ type ItemCategory struct {
 	Title   string   `xml:",chardata"`
 }
 
+// get slug of term as used in urls, falls back to title for terms without
+// nicename
+func (c *ItemCategory) Slug() string {
+	return TermSlug(c.Name, c.Title)
+}
+
 type ItemMeta struct {
 	Key   string `xml:"http://wordpress.org/export/1.2/ meta_key"`
 	Value string `xml:"http://wordpress.org/export/1.2/ meta_value"`
 }
 
 type ItemComment struct {
-	Id       int           `xml:"http://wordpress.org/export/1.2/ comment_id" yaml:"-"`
-	Author   string        `xml:"http://wordpress.org/export/1.2/ comment_author" yaml:"author"`
-	Date     wp_date       `xml:"http://wordpress.org/export/1.2/ comment_date" yaml:"date"`
-	Content  string        `xml:"http://wordpress.org/export/1.2/ comment_content" yaml:"content"`
-	ParentId int           `xml:"http://wordpress.org/export/1.2/ comment_parent" yaml:"-"`
-	Comments []ItemComment `yaml:"comments,omitempty"`
+	Id      int     `xml:"http://wordpress.org/export/1.2/ comment_id" yaml:"-" json:"-"`
+	Author  string  `xml:"http://wordpress.org/export/1.2/ comment_author" yaml:"author" json:"author"`
+	Date    wp_date `xml:"http://wordpress.org/export/1.2/ comment_date" yaml:"date" json:"date"`
+	DateGmt wp_date `xml:"http://wordpress.org/export/1.2/ comment_date_gmt" yaml:"-" json:"-"`
+	Content string  `xml:"http://wordpress.org/export/1.2/ comment_content" yaml:"content" json:"content"`
+	// sanitized html of content (for comment systems requiring html)
+	ContentHtml string `yaml:"-" json:"-"`
+	ParentId    int    `xml:"http://wordpress.org/export/1.2/ comment_parent" yaml:"-" json:"-"`
+	Approved    string `xml:"http://wordpress.org/export/1.2/ comment_approved" yaml:"-" json:"-"`
+	Type        string `xml:"http://wordpress.org/export/1.2/ comment_type" yaml:"type,omitempty" json:"type,omitempty"`
+	// private data, never exported (scrubbed before comments are written)
+	AuthorEmail string `xml:"http://wordpress.org/export/1.2/ comment_author_email" yaml:"-" json:"-"`
+	AuthorIp    string `xml:"http://wordpress.org/export/1.2/ comment_author_IP" yaml:"-" json:"-"`
+	// public data derived from author details
+	AuthorUrl      string        `xml:"http://wordpress.org/export/1.2/ comment_author_url" yaml:"url,omitempty" json:"url,omitempty"`
+	AuthorUrlRel   string        `yaml:"url_rel,omitempty" json:"url_rel,omitempty"`
+	GravatarMd5    string        `yaml:"gravatar_md5,omitempty" json:"gravatar_md5,omitempty"`
+	GravatarSha256 string        `yaml:"gravatar_sha256,omitempty" json:"gravatar_sha256,omitempty"`
+	Comments       []ItemComment `yaml:"comments,omitempty" json:"comments,omitempty"`
+}
+
+// get comment date in UTC, local date is used for comments without gmt date
+func (c *ItemComment) GetDateGmt() time.Time {
+	return getGmtDate(c.Date, c.DateGmt)
 }
 
-func (item *Item) GetTaxonomies() (map[string][]string, error) {
+// get item terms (slugs) grouped by taxonomy, taxonomies are named by their
+// domains unless renamed by names map
+func (item *Item) GetTaxonomies(names map[string]string) map[string][]string {
 
 	result := map[string][]string{}
 
 	for i := 0; i < len(item.Categories); i++ {
 		c := item.Categories[i]
-		switch c.Domain {
-		case "post_tag":
-			result["tags"] = append(result["tags"], c.Title)
-		case "category":
-			result["categories"] = append(result["categories"], c.Title)
-		default:
-			return result, fmt.Errorf("Unknown taxonomy (domain): %s", c.Domain)
+
+		name, ok := names[c.Domain]
+		if !ok {
+			name = c.Domain
 		}
+
+		result[name] = append(result[name], c.Slug())
+	}
+
+	return result
+}
+
+// TermSlug returns decoded term nicename (or title if nicename is empty)
+func TermSlug(name string, title string) string {
+	if name == "" {
+		return title
+	}
+
+	if decoded, err := url.PathUnescape(name); err == nil {
+		return decoded
 	}
 
-	return result, nil
+	return name
 }
@@ -0,0 +1,178 @@
+package wordpress
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ItemPathData holds item fields available in content path templates
+type ItemPathData struct {
+	Year     string
+	Month    string
+	Day      string
+	Slug     string
+	Id       string
+	Type     string
+	Category string
+	Author   string
+	// slugs of parent pages (top down) joined by slash
+	Parents string
+}
+
+// DefaultPathTemplates returns content path templates (relative to content
+// dir) per post type
+func DefaultPathTemplates() map[string]string {
+	return map[string]string{
+		"post": "posts/{{.Year}}/{{.Year}}_{{.Month}}_{{.Day}}_{{.Slug}}",
+		"page": "pages/{{.Parents}}/{{.Slug}}",
+	}
+}
+
+// render content path templates and normalized slugs for all exported
+// items. Collisions (two items rendered to the same directory or to the same
+// url) are reported and resolved by numeric suffixes, items with lower ids
+// keep original paths
+func (w *WpExport) prepareItemDirs() error {
+
+	templates := map[string]*template.Template{}
+	for post_type, text := range w.ConfigPathTemplates {
+		t, err := template.New(post_type).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return fmt.Errorf("Invalid path template for %s: %v", post_type, err)
+		}
+		templates[post_type] = t
+	}
+
+	w.item_dirs = map[int]string{}
+	w.item_slugs = map[int]string{}
+
+	// parents are processed before children (their slugs are part of
+	// children paths), items on the same level are ordered by id
+	var items []*Item
+	depths := map[int]int{}
+	ch := w.channel
+	for i := 0; i < len(ch.Items); i++ {
+		if w.IsExported(&ch.Items[i]) {
+			items = append(items, &ch.Items[i])
+			depths[ch.Items[i].Id] = len(w.FindParentItems(&ch.Items[i]))
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if depths[items[i].Id] != depths[items[j].Id] {
+			return depths[items[i].Id] < depths[items[j].Id]
+		}
+		return items[i].Id < items[j].Id
+	})
+
+	used_dirs := map[string]*Item{}
+	used_urls := map[string]*Item{}
+
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		t, ok := templates[item.Type]
+		if !ok {
+			return fmt.Errorf("No path template for post type %s", item.Type)
+		}
+
+		slug := w.getItemSlug(item)
+		data := w.getItemPathData(item)
+
+		var base_dir string
+		for n := 1; ; n++ {
+			data.Slug = slug
+			if n > 1 {
+				data.Slug = fmt.Sprintf("%s-%d", slug, n)
+			}
+
+			var buf bytes.Buffer
+			if err := t.Execute(&buf, data); err != nil {
+				return fmt.Errorf("Rendering path of %s (%d) failed: %v", item.Title, item.Id, err)
+			}
+			item_dir := filepath.Join(w.hugo_content, filepath.FromSlash(buf.String()))
+
+			// template doesn't depend on slug
+			if n == 1 {
+				base_dir = item_dir
+			} else if item_dir == base_dir {
+				item_dir = fmt.Sprintf("%s-%d", item_dir, n)
+			}
+
+			// hugo url of bundle is derived from parent dir and slug
+			item_url := filepath.Join(filepath.Dir(item_dir), data.Slug)
+
+			other, dir_used := used_dirs[item_dir]
+			if !dir_used {
+				other, dir_used = used_urls[item_url]
+			}
+			if dir_used {
+				w.log.Warningf("Path %s of %s (%d) collides with %s (%d), trying another one", item_dir, item.Title, item.Id, other.Title, other.Id)
+				continue
+			}
+
+			w.item_dirs[item.Id] = item_dir
+			w.item_slugs[item.Id] = data.Slug
+			used_dirs[item_dir] = item
+			used_urls[item_url] = item
+			break
+		}
+	}
+
+	return nil
+}
+
+func (w *WpExport) getItemPathData(item *Item) ItemPathData {
+
+	data := ItemPathData{
+		Year:   item.PostDate.Format("2006"),
+		Month:  item.PostDate.Format("01"),
+		Day:    item.PostDate.Format("02"),
+		Slug:   w.getItemSlug(item),
+		Id:     strconv.Itoa(item.Id),
+		Type:   item.Type,
+		Author: item.Creator,
+	}
+
+	for i := 0; i < len(item.Categories); i++ {
+		if item.Categories[i].Domain == "category" {
+			data.Category = item.Categories[i].Slug()
+			break
+		}
+	}
+
+	parents := w.FindParentItems(item)
+	var parent_slugs []string
+	for i := len(parents) - 1; i >= 0; i-- {
+		parent_slugs = append(parent_slugs, w.getItemSlug(parents[i]))
+	}
+	data.Parents = strings.Join(parent_slugs, "/")
+
+	return data
+}
+
+// get normalized slug of item, slugs of items with already rendered paths
+// (including collision suffixes) are preferred
+func (w *WpExport) getItemSlug(item *Item) string {
+
+	if slug, ok := w.item_slugs[item.Id]; ok {
+		return slug
+	}
+
+	slug := NormalizeSlug(item.Name)
+
+	// drafts have no slugs
+	if slug == "" {
+		slug = NormalizeSlug(item.Title)
+	}
+
+	if slug == "" {
+		slug = item.Type + "-" + strconv.Itoa(item.Id)
+	}
+
+	return slug
+}
@@ -0,0 +1,122 @@
+package wordpress
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// set item url to its original wordpress permalink and collect permalink
+// pattern of item section
+func (w *WpExport) prepareItemPermalink(item *Item, fm *HugoFrontMatter) {
+
+	if !w.ConfigPreservePermalinks {
+		return
+	}
+
+	// id based links (e.g. drafts) are not real permalinks
+	key, ok := w.getLinkKey(item.Link)
+	if !ok || strings.HasPrefix(key, "?") {
+		w.log.Debugf("Item %s (%d) has no permalink to preserve", item.Title, item.Id)
+		return
+	}
+
+	u, err := url.Parse(item.Link)
+	w.check(err)
+
+	fm.Url = u.Path
+	if !strings.HasSuffix(fm.Url, "/") {
+		fm.Url += "/"
+	}
+
+	section := w.getItemSection(item)
+	if section == "" {
+		w.log.Debugf("Item %s (%d) is not part of any section, its permalink pattern is not collected", item.Title, item.Id)
+		return
+	}
+	if w.permalink_patterns[section] == nil {
+		w.permalink_patterns[section] = map[string]int{}
+	}
+	w.permalink_patterns[section][w.getPermalinkPattern(item, u.Path)]++
+}
+
+// get top level section (content dir) of item as used by hugo permalinks
+// config, empty string is returned for items placed directly in content dir
+func (w *WpExport) getItemSection(item *Item) string {
+	segments := strings.Split(w.getItemKey(w.getItemDir(item)), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+
+	return segments[0]
+}
+
+// derive hugo permalink pattern (e.g. /:year/:month/:slug/) from item path
+func (w *WpExport) getPermalinkPattern(item *Item, item_path string) string {
+
+	slug, err := url.PathUnescape(item.Name)
+	if err != nil {
+		slug = item.Name
+	}
+
+	// date parts are matched in order of wordpress structure tags
+	// (%year%, %monthnum%, %day%), so that equal month and day (or slug
+	// looking like date) are told apart
+	dates := []struct {
+		value string
+		token string
+	}{
+		{item.PostDate.Format("2006"), ":year"},
+		{item.PostDate.Format("01"), ":month"},
+		{item.PostDate.Format("02"), ":day"},
+	}
+
+	next_date := 0
+	segments := strings.Split(strings.Trim(item_path, "/"), "/")
+	for i := 0; i < len(segments); i++ {
+		matched := false
+		for j := next_date; j < len(dates); j++ {
+			if segments[i] == dates[j].value {
+				segments[i] = dates[j].token
+				next_date = j + 1
+				matched = true
+				break
+			}
+		}
+
+		if !matched && segments[i] == slug {
+			segments[i] = ":slug"
+		}
+	}
+
+	return "/" + strings.Join(segments, "/") + "/"
+}
+
+// get permalink patterns of sections where all items share the same
+// pattern containing slug
+func (w *WpExport) getPermalinks() map[string]string {
+
+	result := map[string]string{}
+
+	var sections []string
+	for section := range w.permalink_patterns {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	for i := 0; i < len(sections); i++ {
+		patterns := w.permalink_patterns[sections[i]]
+		if len(patterns) != 1 {
+			w.log.Infof("Permalinks of %s are not uniform (%d patterns), no permalinks config generated", sections[i], len(patterns))
+			continue
+		}
+
+		for pattern := range patterns {
+			if strings.Contains(pattern, ":slug") {
+				result[sections[i]] = pattern
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,33 @@
+package wordpress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetPermalinkPattern(t *testing.T) {
+	tests := []struct {
+		name string
+		slug string
+		date time.Time
+		path string
+		want string
+	}{
+		{"date and slug", "hello", time.Date(2019, 5, 2, 10, 0, 0, 0, time.UTC), "/2019/05/02/hello/", "/:year/:month/:day/:slug/"},
+		{"month equals day", "hello", time.Date(2019, 5, 5, 10, 0, 0, 0, time.UTC), "/2019/05/05/hello/", "/:year/:month/:day/:slug/"},
+		{"all date parts equal", "hello", time.Date(2001, 1, 1, 10, 0, 0, 0, time.UTC), "/2001/01/01/hello/", "/:year/:month/:day/:slug/"},
+		{"year and month", "hello", time.Date(2019, 3, 3, 10, 0, 0, 0, time.UTC), "/2019/03/hello/", "/:year/:month/:slug/"},
+		{"slug only", "hello", time.Date(2019, 5, 2, 10, 0, 0, 0, time.UTC), "/hello/", "/:slug/"},
+		{"static prefix", "hello", time.Date(2019, 5, 2, 10, 0, 0, 0, time.UTC), "/blog/2019/hello/", "/blog/:year/:slug/"},
+		{"slug looking like year", "2019", time.Date(2019, 5, 2, 10, 0, 0, 0, time.UTC), "/2019/2019/", "/:year/:slug/"},
+		{"encoded slug", "%c4%8dtvrtek", time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC), "/2019/06/čtvrtek/", "/:year/:month/:slug/"},
+	}
+
+	w := &WpExport{}
+	for _, tt := range tests {
+		item := Item{Name: tt.slug, PostDate: wp_date{tt.date}}
+		if got := w.getPermalinkPattern(&item, tt.path); got != tt.want {
+			t.Errorf("%s: getPermalinkPattern(%q) = %q, want %q", tt.name, tt.path, got, tt.want)
+		}
+	}
+}
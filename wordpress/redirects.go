@@ -0,0 +1,190 @@
+package wordpress
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// supported formats of redirect maps
+const (
+	REDIRECTS_ALIASES = "aliases"
+	REDIRECTS_NETLIFY = "netlify"
+	REDIRECTS_NGINX   = "nginx"
+	REDIRECTS_APACHE  = "apache"
+	REDIRECTS_CSV     = "csv"
+)
+
+// Redirect represents single redirect from old site path (optionally with
+// query string) to new one, both paths are percent-encoded
+type Redirect struct {
+	From string
+	To   string
+}
+
+// check if redirect map of given format should be generated
+func (w *WpExport) hasRedirectFormat(format string) bool {
+	for i := 0; i < len(w.ConfigRedirectFormats); i++ {
+		if w.ConfigRedirectFormats[i] == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collect redirects from original item urls (permalink and id based url) to
+// new hugo url, paths are also stored as hugo aliases if requested
+func (w *WpExport) prepareItemRedirects(item *Item, fm *HugoFrontMatter, item_dir string) {
+
+	new_url := w.getItemUrl(item_dir, fm)
+
+	// old urls are compared and stored as aliases decoded
+	var old_urls []string
+
+	if key, ok := w.getLinkKey(item.Link); ok && !strings.HasPrefix(key, "?") {
+		u, err := url.Parse(item.Link)
+		w.check(err)
+		old_urls = append(old_urls, u.Path)
+	}
+
+	id := strconv.Itoa(item.Id)
+	if item.Type == "page" {
+		old_urls = append(old_urls, "/?page_id="+id)
+	} else {
+		old_urls = append(old_urls, "/?p="+id)
+	}
+
+	for i := 0; i < len(old_urls); i++ {
+		if strings.TrimSuffix(old_urls[i], "/") == strings.TrimSuffix(new_url, "/") {
+			continue
+		}
+
+		w.addRedirect(old_urls[i], new_url)
+
+		// hugo aliases don't support query strings
+		if w.hasRedirectFormat(REDIRECTS_ALIASES) && !strings.Contains(old_urls[i], "?") {
+			fm.Aliases = append(fm.Aliases, old_urls[i])
+		}
+	}
+}
+
+// add redirect between decoded site paths (old one optionally with query
+// string), paths are percent-encoded as they appear in requests
+func (w *WpExport) addRedirect(from string, to string) {
+	from_path, query := splitRedirectUrl(from)
+	from = escapeRedirectPath(from_path)
+	if query != "" {
+		from += "?" + query
+	}
+
+	w.redirects = append(w.redirects, Redirect{From: from, To: escapeRedirectPath(to)})
+}
+
+func escapeRedirectPath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// write collected redirects to files of all requested formats
+func (w *WpExport) writeRedirects() {
+
+	if len(w.redirects) == 0 {
+		return
+	}
+
+	for i := 0; i < len(w.ConfigRedirectFormats); i++ {
+		switch w.ConfigRedirectFormats[i] {
+		case REDIRECTS_NETLIFY:
+			w.writeRedirectsFile(filepath.Join(w.hugo_static, "_redirects"), w.formatNetlifyRedirect, "", "")
+		case REDIRECTS_NGINX:
+			w.writeRedirectsFile(filepath.Join(w.hugo_root, "redirects.nginx.conf"), w.formatNginxRedirect,
+				"map $request_uri $redirect_uri {\n", "}\n")
+		case REDIRECTS_APACHE:
+			w.writeRedirectsFile(filepath.Join(w.hugo_static, ".htaccess"), w.formatApacheRedirect,
+				"RewriteEngine On\n", "")
+		case REDIRECTS_CSV:
+			w.writeRedirectsCsv(filepath.Join(w.hugo_root, "redirects.csv"))
+		}
+	}
+}
+
+func (w *WpExport) writeRedirectsFile(file_path string, format func(r Redirect) string, header string, footer string) {
+
+	w.log.Infof("Writing %d redirects to file: %s", len(w.redirects), file_path)
+
+	f, err := os.Create(file_path)
+	w.check(err)
+
+	// It’s idiomatic to defer a Close immediately after opening a file.
+	defer f.Close()
+
+	w.file_write_str(f, header)
+	for i := 0; i < len(w.redirects); i++ {
+		w.file_write_str(f, format(w.redirects[i]))
+	}
+	w.file_write_str(f, footer)
+}
+
+func (w *WpExport) writeRedirectsCsv(file_path string) {
+
+	w.log.Infof("Writing %d redirects to file: %s", len(w.redirects), file_path)
+
+	f, err := os.Create(file_path)
+	w.check(err)
+
+	// It’s idiomatic to defer a Close immediately after opening a file.
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	w.check(writer.Write([]string{"from", "to"}))
+	for i := 0; i < len(w.redirects); i++ {
+		w.check(writer.Write([]string{w.redirects[i].From, w.redirects[i].To}))
+	}
+	writer.Flush()
+	w.check(writer.Error())
+}
+
+// split old url to path and query string
+func splitRedirectUrl(from string) (string, string) {
+	if pos := strings.Index(from, "?"); pos >= 0 {
+		return from[:pos], from[pos+1:]
+	}
+	return from, ""
+}
+
+// netlify _redirects line, query parameters are matched separately
+func (w *WpExport) formatNetlifyRedirect(r Redirect) string {
+	from_path, query := splitRedirectUrl(r.From)
+	if query != "" {
+		return fmt.Sprintf("%s %s %s 301!\n", from_path, strings.ReplaceAll(query, "&", " "), r.To)
+	}
+	return fmt.Sprintf("%s %s 301\n", from_path, r.To)
+}
+
+// nginx map entry, it is expected to be used together with
+// `if ($redirect_uri) { return 301 $redirect_uri; }`
+func (w *WpExport) formatNginxRedirect(r Redirect) string {
+	return fmt.Sprintf("    \"%s\" \"%s\";\n", r.From, r.To)
+}
+
+// apache mod_rewrite rule, rules are matched against decoded paths and
+// targets are already encoded (NE flag)
+func (w *WpExport) formatApacheRedirect(r Redirect) string {
+	from_path, query := splitRedirectUrl(r.From)
+	if decoded, err := url.PathUnescape(from_path); err == nil {
+		from_path = decoded
+	}
+	rule := fmt.Sprintf("RewriteRule ^%s/?$ %s [R=301,NE,L]\n",
+		regexp.QuoteMeta(strings.Trim(from_path, "/")), r.To)
+	if query != "" {
+		// trailing ? drops original query string
+		rule = fmt.Sprintf("RewriteCond %%{QUERY_STRING} ^%s$\nRewriteRule ^%s$ %s? [R=301,NE,L]\n",
+			regexp.QuoteMeta(query), regexp.QuoteMeta(strings.Trim(from_path, "/")), r.To)
+	}
+	return rule
+}
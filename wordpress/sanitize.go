@@ -0,0 +1,292 @@
+package wordpress
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// formats of exported comment content
+const (
+	COMMENTS_CONTENT_MARKDOWN = "markdown"
+	COMMENTS_CONTENT_HTML     = "html"
+)
+
+// elements allowed in sanitized html together with their allowed attributes
+var sanitize_allowed = map[string][]string{
+	"a":          {"href", "title"},
+	"p":          {},
+	"br":         {},
+	"strong":     {},
+	"b":          {},
+	"em":         {},
+	"i":          {},
+	"u":          {},
+	"s":          {},
+	"del":        {},
+	"code":       {},
+	"pre":        {},
+	"blockquote": {"cite"},
+	"q":          {"cite"},
+	"cite":       {},
+	"ul":         {},
+	"ol":         {},
+	"li":         {},
+}
+
+// elements dropped together with their content, other elements which are not
+// allowed are replaced by their content
+var sanitize_dropped = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"form":     true,
+	"input":    true,
+	"textarea": true,
+	"select":   true,
+	"button":   true,
+	"noscript": true,
+	"template": true,
+}
+
+// url schemes allowed in href and cite attributes
+var sanitize_schemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// convert comment content (html fragment with wordpress auto paragraphs) to
+// sanitized html or markdown according to configuration
+func (w *WpExport) prepareCommentContent(c *ItemComment) {
+
+	sanitized := SanitizeHtml(Autop(c.Content))
+	c.ContentHtml = sanitized
+
+	if w.ConfigCommentsContent == COMMENTS_CONTENT_HTML {
+		c.Content = sanitized
+		return
+	}
+
+	content, err := convertCommentMarkdown(sanitized)
+	if err != nil {
+		w.log.Warningf("Unable to convert comment %d to markdown, keeping sanitized html: %v", c.Id, err)
+		c.Content = sanitized
+		return
+	}
+	c.Content = content
+}
+
+// characters escaped in text of comments converted to markdown, besides
+// formatting characters also html tags, entities and link brackets are
+// escaped, so that text is not rendered as markup again
+var markdown_text_escapes = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`|`, `\|`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`&`, `\&`,
+)
+
+// block markers (headings, lists, setext underlines) at line starts
+var markdown_text_blocks = regexp.MustCompile(`(?m)^(\s*)([#+=-])`)
+var markdown_text_ordered = regexp.MustCompile(`(?m)^(\s*\d+)([.)])`)
+
+// html whitespace (including line breaks) collapsed to single space
+var markdown_text_spaces = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// characters of urls which could end markdown link destination
+var markdown_url_escapes = strings.NewReplacer(
+	`(`, "%28",
+	`)`, "%29",
+	`<`, "%3C",
+	`>`, "%3E",
+	` `, "%20",
+)
+
+// convert sanitized html of comment to markdown, text is escaped so that
+// only markup of sanitized html is rendered by markdown
+func convertCommentMarkdown(sanitized string) (string, error) {
+
+	options := md.Options{
+		GetAbsoluteURL: func(selec *goquery.Selection, raw string, domain string) string {
+			return markdown_url_escapes.Replace(raw)
+		},
+	}
+
+	converter := md.NewConverter("", true, &options)
+	converter.AddRules(md.Rule{
+		Filter: []string{"#text"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			text := selec.Text()
+			if strings.TrimSpace(text) == "" {
+				return md.String("")
+			}
+
+			text = markdown_text_spaces.ReplaceAllString(text, " ")
+			text = markdown_text_escapes.Replace(text)
+			text = markdown_text_blocks.ReplaceAllString(text, `$1\$2`)
+			text = markdown_text_ordered.ReplaceAllString(text, `$1\$2`)
+
+			// leading spaces of list items would break indentation
+			if md.IndexWithText(selec) == 0 && selec.Parent().Is("li") {
+				text = strings.Trim(text, " ")
+			}
+
+			return &text
+		},
+	}, md.Rule{
+		// line break within paragraph (default rule starts new paragraph)
+		Filter: []string{"br"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			return md.String("\\\n")
+		},
+	})
+
+	return converter.ConvertString(sanitized)
+}
+
+var autop_blocks = regexp.MustCompile(`(?i)^<(p|div|pre|blockquote|ul|ol|li|h[1-6]|table|dl|hr|figure)[\s>/]`)
+var autop_paragraphs = regexp.MustCompile(`\n\s*\n`)
+var autop_newlines = regexp.MustCompile(`(?i)(<br\s*/?>)?[ \t]*\n`)
+
+// Autop wraps blocks of text separated by empty lines to paragraphs and
+// converts remaining new lines to line breaks, as wpautop() does when
+// wordpress renders content. Text already containing paragraphs is kept
+func Autop(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSpace(text)
+
+	if text == "" || strings.Contains(strings.ToLower(text), "<p>") {
+		return text
+	}
+
+	blocks := autop_paragraphs.Split(text, -1)
+	for i := 0; i < len(blocks); i++ {
+		block := strings.TrimSpace(blocks[i])
+		if autop_blocks.MatchString(block) {
+			blocks[i] = block
+			continue
+		}
+
+		// new lines already preceded by line break are kept
+		block = autop_newlines.ReplaceAllStringFunc(block, func(newline string) string {
+			if strings.HasPrefix(newline, "<") {
+				return newline
+			}
+			return "<br />\n"
+		})
+		blocks[i] = "<p>" + block + "</p>"
+	}
+
+	return strings.Join(blocks, "\n")
+}
+
+// SanitizeHtml renders html fragment again using allow-list of elements and
+// attributes, links are marked as untrusted and smilies are replaced by
+// their text
+func SanitizeHtml(fragment string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return html.EscapeString(fragment)
+	}
+
+	var b strings.Builder
+	body := doc.Find("body")
+	for i := 0; i < len(body.Nodes); i++ {
+		for n := body.Nodes[i].FirstChild; n != nil; n = n.NextSibling {
+			sanitizeNode(n, &b)
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func sanitizeNode(n *html.Node, b *strings.Builder) {
+
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		// comments, doctypes etc.
+		return
+	}
+
+	tag := strings.ToLower(n.Data)
+
+	if sanitize_dropped[tag] {
+		return
+	}
+
+	// smilies are kept as text
+	if tag == "img" {
+		if alt := getNodeAttr(n, "alt"); alt != "" && strings.Contains(getNodeAttr(n, "class"), "wp-smiley") {
+			b.WriteString(html.EscapeString(alt))
+		}
+		return
+	}
+
+	allowed_attrs, allowed := sanitize_allowed[tag]
+	if allowed {
+		b.WriteString("<" + tag)
+		for i := 0; i < len(allowed_attrs); i++ {
+			value := getNodeAttr(n, allowed_attrs[i])
+			if value == "" {
+				continue
+			}
+			if allowed_attrs[i] == "href" || allowed_attrs[i] == "cite" {
+				if !isSafeUrl(value) {
+					continue
+				}
+			}
+			b.WriteString(" " + allowed_attrs[i] + `="` + html.EscapeString(value) + `"`)
+		}
+		if tag == "a" {
+			b.WriteString(` rel="` + COMMENT_URL_REL + `"`)
+		}
+		b.WriteString(">")
+	}
+
+	if tag == "br" {
+		return
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(c, b)
+	}
+
+	if allowed {
+		b.WriteString("</" + tag + ">")
+	}
+}
+
+func getNodeAttr(n *html.Node, name string) string {
+	for i := 0; i < len(n.Attr); i++ {
+		if strings.EqualFold(n.Attr[i].Key, name) {
+			return n.Attr[i].Val
+		}
+	}
+	return ""
+}
+
+// check if url is absolute with allowed scheme or relative
+func isSafeUrl(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "" || sanitize_schemes[strings.ToLower(u.Scheme)]
+}
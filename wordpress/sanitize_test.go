@@ -0,0 +1,117 @@
+package wordpress
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/op/go-logging"
+)
+
+func TestAutop(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "  ", ""},
+		{"single line", "Hello", "<p>Hello</p>"},
+		{"line breaks", "one\ntwo", "<p>one<br />\ntwo</p>"},
+		{"existing line breaks", "one<br />\ntwo<BR>\nthree", "<p>one<br />\ntwo<BR>\nthree</p>"},
+		{"paragraphs", "one\r\n\r\ntwo", "<p>one</p>\n<p>two</p>"},
+		{"blocks kept", "<blockquote>quote</blockquote>\n\ntext", "<blockquote>quote</blockquote>\n<p>text</p>"},
+		{"existing paragraphs", "<p>one</p>\n\n<p>two</p>", "<p>one</p>\n\n<p>two</p>"},
+	}
+
+	for _, tt := range tests {
+		if got := Autop(tt.in); got != tt.want {
+			t.Errorf("%s: Autop(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeHtml(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"allowed formatting", "<p>Nice <b>post</b></p>", "<p>Nice <b>post</b></p>"},
+		{"script dropped", "a<script>alert(1)</script>b", "ab"},
+		{"unknown element unwrapped", `<span class="x">text</span>`, "text"},
+		{"attributes dropped", `<p onclick="alert(1)" style="x">text</p>`, "<p>text</p>"},
+		{"link marked", `<a href="http://example.com/" onclick="x">link</a>`, `<a href="http://example.com/" rel="nofollow ugc">link</a>`},
+		{"unsafe scheme dropped", `<a href="javascript:alert(1)">link</a>`, `<a rel="nofollow ugc">link</a>`},
+		{"images dropped", `<img src="x" onerror="alert(1)">`, ""},
+		{"smiley kept as text", `<img src="smile.gif" alt=":)" class="wp-smiley">`, ":)"},
+		{"text escaped", "&lt;script&gt; a &amp; b", "&lt;script&gt; a &amp; b"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeHtml(tt.in); got != tt.want {
+			t.Errorf("%s: SanitizeHtml(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConvertCommentMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"formatting", "<p>Nice <b>post</b></p>", "Nice **post**"},
+		{"link", `<p><a href="http://example.com/" rel="nofollow ugc">link</a></p>`, "[link](http://example.com/)"},
+		{"link destination escaped", `<a href="http://example.com/a)b (c">link</a>`, "[link](http://example.com/a%29b%20%28c)"},
+		{"html tags escaped", "<p>&lt;img src=x onerror=alert(1)&gt;</p>", `\<img src=x onerror=alert(1)\>`},
+		{"links escaped", "<p>[x](javascript:alert(1))</p>", `\[x\](javascript:alert(1))`},
+		{"entities escaped", "<p>&amp;lt;b&amp;gt;</p>", `\&lt;b\&gt;`},
+		{"formatting escaped", "<p>*a* _b_ `c` \\d</p>", "\\*a\\* \\_b\\_ \\`c\\` \\\\d"},
+		{"block markers escaped", "<p># heading<br />\n- item<br />\n1. item</p>", "\\# heading\\\n\\- item\\\n1\\. item"},
+		{"newlines collapsed", "<p>one\ntwo\r\n  three</p>", "one two three"},
+		{"code kept", "<p><code>a &lt; b</code></p>", "`a < b`"},
+	}
+
+	for _, tt := range tests {
+		got, err := convertCommentMarkdown(tt.in)
+		if err != nil {
+			t.Errorf("%s: convertCommentMarkdown(%q) failed: %v", tt.name, tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: convertCommentMarkdown(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+// unescaped html tags and link destinations
+var markdown_live_markup = regexp.MustCompile(`(^|[^\\])(<[a-z/]|\]\()`)
+
+func TestPrepareCommentContent(t *testing.T) {
+	raw := "&lt;script&gt;alert(1)&lt;/script&gt; <script>alert(2)</script>[x](javascript:alert(3))\n\n<a href=\"javascript:alert(4)\">link</a>"
+
+	w := NewWpExport(logging.MustGetLogger("test"))
+
+	c := ItemComment{Content: raw}
+	w.prepareCommentContent(&c)
+
+	want_html := "<p>&lt;script&gt;alert(1)&lt;/script&gt; [x](javascript:alert(3))</p>\n" +
+		`<p><a rel="nofollow ugc">link</a></p>`
+	if c.ContentHtml != want_html {
+		t.Errorf("ContentHtml = %q, want %q", c.ContentHtml, want_html)
+	}
+
+	want_markdown := `\<script\>alert(1)\</script\> \[x\](javascript:alert(3))` + "\n\nlink"
+	if c.Content != want_markdown {
+		t.Errorf("Content = %q, want %q", c.Content, want_markdown)
+	}
+	if live := markdown_live_markup.FindString(c.Content); live != "" {
+		t.Errorf("Content %q contains live markup %q", c.Content, live)
+	}
+
+	w.ConfigCommentsContent = COMMENTS_CONTENT_HTML
+	c = ItemComment{Content: raw}
+	w.prepareCommentContent(&c)
+	if c.Content != want_html {
+		t.Errorf("Content in html mode = %q, want %q", c.Content, want_html)
+	}
+}
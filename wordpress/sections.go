@@ -0,0 +1,107 @@
+package wordpress
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// HugoSectionFrontMatter is front matter of generated section list pages
+// (e.g. year sections of posts)
+type HugoSectionFrontMatter struct {
+	Title string `yaml:"title"`
+}
+
+// find directories which become hugo sections, i.e. directories containing
+// other items. Items rendered to such directories are written to _index.md
+// (branch bundle), other sections get generated list pages. Both are known
+// before any item is written, so result doesn't depend on processing order
+func (w *WpExport) prepareSections() {
+
+	item_dirs := map[string]bool{}
+	for _, dir := range w.item_dirs {
+		item_dirs[dir] = true
+	}
+
+	w.section_dirs = map[string]bool{}
+	list_dirs := map[string]bool{}
+
+	for _, dir := range w.item_dirs {
+		for parent := filepath.Dir(dir); parent != w.hugo_content && strings.HasPrefix(parent, w.hugo_content); parent = filepath.Dir(parent) {
+			if item_dirs[parent] {
+				w.section_dirs[parent] = true
+			} else {
+				list_dirs[parent] = true
+			}
+		}
+	}
+
+	var dirs []string
+	for dir := range list_dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for i := 0; i < len(dirs); i++ {
+		w.ensure_dir(dirs[i])
+		w.writeListPage(dirs[i], &HugoSectionFrontMatter{Title: getSectionTitle(filepath.Base(dirs[i]))})
+	}
+
+	w.log.Infof("Sections prepared, item sections: %d, generated sections: %d", len(w.section_dirs), len(dirs))
+}
+
+// get name of content file of item, items containing other items are
+// written as branch bundles
+func (w *WpExport) getItemIndexFile(item_dir string) string {
+	if w.section_dirs[item_dir] {
+		return "_index.md"
+	}
+	return "index.md"
+}
+
+// get title of generated section from its directory name, years are kept as
+// they are, other names are turned to words
+func getSectionTitle(name string) string {
+	title := strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(name))
+	if title == "" {
+		return name
+	}
+
+	runes := []rune(title)
+	runes[0] = unicode.ToUpper(runes[0])
+
+	return string(runes)
+}
+
+// write list page (_index.md) with given front matter only to dir, existing
+// list pages (possibly edited since previous export) are kept unless
+// overwriting is requested
+func (w *WpExport) writeListPage(dir string, fm interface{}) {
+
+	file_path := filepath.Join(dir, "_index.md")
+
+	if _, err := os.Stat(file_path); err == nil && !w.ConfigOverwriteListPages {
+		w.log.Debugf("List page %s exists, keeping existing content (see --overwrite-list-pages)", file_path)
+		return
+	}
+
+	w.log.Debugf("Writing list page to file: %s", file_path)
+
+	front_matter_bytes, err := yaml.Marshal(fm)
+	w.check(err)
+
+	f, err := os.Create(file_path)
+	w.check(err)
+
+	// It’s idiomatic to defer a Close immediately after opening a file.
+	defer f.Close()
+
+	w.file_write_str(f, "---\n")
+	_, err = f.Write(front_matter_bytes)
+	w.check(err)
+	w.file_write_str(f, "---\n")
+}
@@ -0,0 +1,111 @@
+package wordpress
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// names of hugo config files which are respected when looking for existing
+// site configuration
+var SITE_CONFIG_FILES = []string{
+	"hugo.toml", "hugo.yaml", "hugo.yml", "hugo.json",
+	"config.toml", "config.yaml", "config.yml", "config.json",
+}
+
+// HugoConfig is site configuration generated from channel metadata
+type HugoConfig struct {
+	BaseURL                string         `yaml:"baseURL"`
+	Title                  string         `yaml:"title"`
+	LanguageCode           string         `yaml:"languageCode,omitempty"`
+	DefaultContentLanguage string         `yaml:"defaultContentLanguage,omitempty"`
+	Pagination             HugoPagination `yaml:"pagination"`
+	Params                 HugoParams     `yaml:"params,omitempty"`
+}
+
+type HugoPagination struct {
+	PagerSize int `yaml:"pagerSize"`
+}
+
+type HugoParams struct {
+	Description string `yaml:"description,omitempty"`
+}
+
+// build site configuration from channel metadata
+func (w *WpExport) getSiteConfig() HugoConfig {
+
+	ch := w.channel
+
+	config := HugoConfig{
+		BaseURL:      strings.TrimSuffix(ch.Link, "/") + "/",
+		Title:        ch.Title,
+		LanguageCode: ch.Language,
+		Pagination:   HugoPagination{PagerSize: w.ConfigPagerSize},
+		Params:       HugoParams{Description: ch.Description},
+	}
+
+	// e.g. cs-CZ -> cs
+	if ch.Language != "" {
+		config.DefaultContentLanguage = strings.ToLower(strings.SplitN(ch.Language, "-", 2)[0])
+	}
+
+	return config
+}
+
+// write generated site configuration to config.yaml in site root, existing
+// configuration (in any supported format) is kept unless overwriting is
+// requested. Data collected during export (taxonomies, permalinks and menus)
+// are always written to config dir, which hugo merges with site config
+func (w *WpExport) writeSiteConfig() {
+
+	// hugo default taxonomies are kept only if they are configured
+	// explicitly
+	if len(w.taxonomies) > 0 {
+		w.writeConfigFragment("taxonomies", w.taxonomies)
+	}
+
+	if permalinks := w.getPermalinks(); len(permalinks) > 0 {
+		w.writeConfigFragment("permalinks", permalinks)
+	}
+
+	if menus := w.getMenus(); len(menus) > 0 {
+		w.writeConfigFragment("menus", menus)
+	}
+
+	for i := 0; i < len(SITE_CONFIG_FILES); i++ {
+		existing := filepath.Join(w.hugo_root, SITE_CONFIG_FILES[i])
+		if _, err := os.Stat(existing); err == nil {
+			if !w.ConfigOverwriteConfig {
+				w.log.Warningf("Site config %s already exists, keeping it (see --overwrite-config)", existing)
+				return
+			}
+			if SITE_CONFIG_FILES[i] != "config.yaml" {
+				w.log.Warningf("Site config %s exists, generated config.yaml may be ignored by hugo", existing)
+			}
+		}
+	}
+
+	file_path := filepath.Join(w.hugo_root, "config.yaml")
+
+	w.log.Infof("Writing site config to file: %s", file_path)
+
+	w.writeYaml(w.getSiteConfig(), file_path)
+}
+
+// write single top level key of site config to config/_default/<key>.yaml,
+// existing file (possibly edited by hand) is kept as site config is
+func (w *WpExport) writeConfigFragment(key string, data interface{}) {
+
+	config_dir := filepath.Join(w.hugo_root, "config", "_default")
+	w.ensure_dir(config_dir)
+
+	file_path := filepath.Join(config_dir, key+".yaml")
+	if _, err := os.Stat(file_path); err == nil && !w.ConfigOverwriteConfig {
+		w.log.Warningf("Site config %s already exists, keeping it (see --overwrite-config)", file_path)
+		return
+	}
+
+	w.log.Infof("Writing %s config to file: %s", key, file_path)
+
+	w.writeYaml(data, file_path)
+}
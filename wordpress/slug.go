@@ -0,0 +1,55 @@
+package wordpress
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// letters which are not decomposed to base letter and combining marks
+var slug_transliterations = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'ð': "d",
+	'þ': "th",
+	'ı': "i",
+}
+
+// NormalizeSlug converts wordpress post name (or title) to ascii slug,
+// percent encoded names are decoded and letters with diacritics are
+// transliterated (e.g. "%c4%8dtvrtek" -> "ctvrtek")
+func NormalizeSlug(name string) string {
+
+	if decoded, err := url.PathUnescape(name); err == nil {
+		name = decoded
+	}
+
+	var b strings.Builder
+
+	// decomposition splits letters with diacritics to base letters and
+	// combining marks, which are dropped
+	dash := false
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case slug_transliterations[r] != "":
+			b.WriteString(slug_transliterations[r])
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,177 @@
+package wordpress
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// taxonomy of navigation menus, it is not exported as hugo taxonomy
+const TAXONOMY_NAV_MENU = "nav_menu"
+
+// pseudo taxonomy of post authors (dc:creator), it is exported as hugo
+// taxonomy to keep author archives
+const TAXONOMY_AUTHOR = "author"
+
+// DefaultTaxonomyNames returns hugo names of wordpress taxonomies (domains),
+// taxonomies not listed here keep their domain names
+func DefaultTaxonomyNames() map[string]string {
+	return map[string]string{
+		"category": "categories",
+		"post_tag": "tags",
+		"author":   "authors",
+	}
+}
+
+// get hugo (plural) name of wordpress taxonomy
+func (w *WpExport) getTaxonomyName(domain string) string {
+	if name, ok := w.ConfigTaxonomyNames[domain]; ok {
+		return name
+	}
+	return domain
+}
+
+// front matter keys predefined by hugo which are not part of HugoFrontMatter
+var taxonomy_reserved_names = []string{
+	"build", "cascade", "description", "draft", "expirydate", "headless",
+	"iscjklanguage", "keywords", "lastmod", "linktitle", "markup", "menu",
+	"menus", "outputs", "params", "publishdate", "sitemap", "summary",
+	"translationkey",
+}
+
+// check that hugo names of all taxonomies used in export don't collide with
+// other front matter keys, taxonomies are stored inline in front matter
+func (w *WpExport) checkTaxonomyNames() error {
+
+	reserved := map[string]bool{}
+	for i := 0; i < len(taxonomy_reserved_names); i++ {
+		reserved[taxonomy_reserved_names[i]] = true
+	}
+
+	t := reflect.TypeOf(HugoFrontMatter{})
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if key != "" {
+			reserved[strings.ToLower(key)] = true
+		}
+	}
+
+	domains := map[string]bool{TAXONOMY_AUTHOR: true}
+	ch := w.channel
+	if len(ch.Categories) > 0 {
+		domains["category"] = true
+	}
+	if len(ch.Tags) > 0 {
+		domains["post_tag"] = true
+	}
+	for i := 0; i < len(ch.Terms); i++ {
+		domains[ch.Terms[i].Taxonomy] = true
+	}
+	for i := 0; i < len(ch.Items); i++ {
+		if !w.IsExported(&ch.Items[i]) {
+			continue
+		}
+		for j := 0; j < len(ch.Items[i].Categories); j++ {
+			domains[ch.Items[i].Categories[j].Domain] = true
+		}
+	}
+	delete(domains, TAXONOMY_NAV_MENU)
+
+	var names []string
+	for domain := range domains {
+		names = append(names, domain)
+	}
+	sort.Strings(names)
+
+	for i := 0; i < len(names); i++ {
+		name := w.getTaxonomyName(names[i])
+		if reserved[strings.ToLower(name)] {
+			return fmt.Errorf("Taxonomy %s would be stored as reserved front matter key %s, rename it in taxonomies config (e.g. %s: %s_terms)", names[i], name, names[i], name)
+		}
+	}
+
+	return nil
+}
+
+// register taxonomy to be included in hugo config, singular name is derived
+// from wordpress domain
+func (w *WpExport) addTaxonomy(domain string) {
+	if domain == TAXONOMY_NAV_MENU {
+		return
+	}
+
+	singular := domain
+	if domain == "post_tag" {
+		singular = "tag"
+	}
+
+	w.taxonomies[singular] = w.getTaxonomyName(domain)
+}
+
+// write list pages (_index.md) of terms defined in channel with their
+// titles, descriptions and parent terms
+func (w *WpExport) writeTermPages() {
+
+	ch := w.channel
+
+	for i := 0; i < len(ch.Categories); i++ {
+		c := ch.Categories[i]
+		fm := HugoTermFrontMatter{
+			Title:       c.Title,
+			Description: c.Description,
+		}
+		if c.Parent != "" {
+			fm.Parent = TermSlug(c.Parent, "")
+		}
+		w.addTaxonomy("category")
+		w.writeTermPage(w.getTaxonomyName("category"), TermSlug(c.Name, c.Title), &fm)
+	}
+
+	for i := 0; i < len(ch.Tags); i++ {
+		t := ch.Tags[i]
+		fm := HugoTermFrontMatter{
+			Title:       t.Title,
+			Description: t.Description,
+		}
+		w.addTaxonomy("post_tag")
+		w.writeTermPage(w.getTaxonomyName("post_tag"), TermSlug(t.Name, t.Title), &fm)
+	}
+
+	for i := 0; i < len(ch.Terms); i++ {
+		t := ch.Terms[i]
+		if t.Taxonomy == TAXONOMY_NAV_MENU {
+			continue
+		}
+		fm := HugoTermFrontMatter{
+			Title:       t.Title,
+			Description: t.Description,
+		}
+		if t.Parent != "" {
+			fm.Parent = TermSlug(t.Parent, "")
+		}
+		w.addTaxonomy(t.Taxonomy)
+		w.writeTermPage(w.getTaxonomyName(t.Taxonomy), TermSlug(t.Name, t.Title), &fm)
+	}
+
+	for i := 0; i < len(ch.Authors); i++ {
+		a := ch.Authors[i]
+		fm := HugoTermFrontMatter{
+			Title: a.DisplayName,
+		}
+		if fm.Title == "" {
+			fm.Title = a.Login
+		}
+		w.addTaxonomy(TAXONOMY_AUTHOR)
+		w.writeTermPage(w.getTaxonomyName(TAXONOMY_AUTHOR), NormalizeSlug(a.Login), &fm)
+	}
+}
+
+func (w *WpExport) writeTermPage(taxonomy string, slug string, fm *HugoTermFrontMatter) {
+
+	term_dir := filepath.Join(w.hugo_content, taxonomy, slug)
+	w.ensure_dir(term_dir)
+
+	w.writeListPage(term_dir, fm)
+}
@@ -0,0 +1,56 @@
+package wordpress
+
+import (
+	"path"
+	"strings"
+)
+
+// PageTemplate describes hugo layout and content type used for pages with
+// given wordpress page template
+type PageTemplate struct {
+	Layout string `mapstructure:"layout"`
+	Type   string `mapstructure:"type"`
+}
+
+// DefaultPageTemplates returns table of wordpress page templates (file names
+// as stored in _wp_page_template meta) mapped to hugo layouts and types
+func DefaultPageTemplates() map[string]PageTemplate {
+	return map[string]PageTemplate{
+		// theme default template, no specific layout
+		"default": {},
+	}
+}
+
+// set page weight from menu order and layout (and type) from page template
+func (w *WpExport) prepareItemLayout(item *Item, fm *HugoFrontMatter) {
+
+	if item.Type != "page" {
+		return
+	}
+
+	fm.Weight = item.MenuOrder
+
+	template, ok := item.GetMeta("_wp_page_template")
+	if !ok || template == "" {
+		return
+	}
+
+	t, ok := w.ConfigPageTemplates[template]
+	if !ok {
+		t = PageTemplate{Layout: getTemplateLayout(template)}
+		w.log.Infof("Page template %s of %s (%d) is not configured, using layout %s", template, item.Title, item.Id, t.Layout)
+	}
+
+	fm.Layout = t.Layout
+	fm.Type = t.Type
+}
+
+// derive name of hugo layout from name of template file, e.g.
+// templates/template-contact.php -> contact
+func getTemplateLayout(template string) string {
+	name := strings.TrimSuffix(path.Base(template), path.Ext(template))
+	for _, prefix := range []string{"template-", "page-", "tpl-"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return NormalizeSlug(name)
+}
@@ -17,6 +17,11 @@ func (c *wp_date) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	const wp_format = "2006-01-02 15:04:05" // yyyy-mm-dd hh:mm:dd date format
 	var v string
 	d.DecodeElement(&v, &start)
+	// unknown dates (e.g. gmt dates of drafts) are kept zero
+	if v == "" || v == "0000-00-00 00:00:00" {
+		*c = wp_date{}
+		return nil
+	}
 	parse, err := time.Parse(wp_format, v)
 	if err != nil {
 		return err
@@ -24,3 +29,12 @@ func (c *wp_date) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	*c = wp_date{parse}
 	return nil
 }
+
+// get gmt date, local date (without timezone) is used when gmt date is
+// unknown
+func getGmtDate(local wp_date, gmt wp_date) time.Time {
+	if gmt.IsZero() {
+		return local.Time
+	}
+	return gmt.Time
+}
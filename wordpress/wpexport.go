@@ -1,19 +1,21 @@
 package wordpress
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 
-	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
 	"github.com/op/go-logging"
 	"gopkg.in/yaml.v2"
 )
@@ -28,10 +30,68 @@ type WpExport struct {
 	hugo_content string
 	hugo_posts   string
 	hugo_pages   string
-
-	ConfigNoDownloads bool
-	ConfigNoComments  bool
-	ConfigOutputDir   string
+	hugo_static  string
+
+	// urls of media exported to static dir mapped to their site paths
+	static_media map[string]string
+
+	// redirects from old site paths to new ones
+	redirects []Redirect
+
+	// bundle directories and normalized slugs of exported items
+	item_dirs  map[int]string
+	item_slugs map[int]string
+
+	// hosts considered as this site (lower case)
+	site_hosts map[string]bool
+
+	// known urls of exported items mapped to hugo content paths
+	link_index map[string]string
+
+	// taxonomies encountered in export, singular names mapped to plural
+	taxonomies map[string]string
+
+	// threads of comments for site wide comment exports
+	comment_threads []CommentThread
+
+	// dirs of items which contain other items
+	section_dirs map[string]bool
+
+	// permalink patterns of items per section with number of occurrences
+	permalink_patterns map[string]map[string]int
+
+	// pipeline of rewriters applied to urls found in item content
+	LinkRewriters []LinkRewriter
+
+	// writers of comments keyed by format
+	CommentWriters map[string]CommentWriter
+
+	ConfigNoDownloads        bool
+	ConfigNoComments         bool
+	ConfigOutputDir          string
+	ConfigAttachmentTypes    map[string]AttachmentType
+	ConfigStaticUploadsPaths bool
+	ConfigUploadsBacklinks   string
+	ConfigImageHosts         []string
+	ConfigSiteAliases        []string
+	ConfigArchiveTypes       map[string]ArchiveType
+	ConfigRedirectFormats    []string
+	ConfigPreservePermalinks bool
+	ConfigPathTemplates      map[string]string
+	ConfigTaxonomyNames      map[string]string
+	ConfigPageTemplates      map[string]PageTemplate
+	ConfigUnapprovedComments bool
+	ConfigPingbacks          string
+	ConfigCommentsFormats    []string
+	ConfigRemark42Site       string
+	ConfigCommentAuthorUrls  string
+	ConfigAnonymizeComments  bool
+	ConfigAnonymizeSalt      string
+	ConfigCommentsContent    string
+	ConfigCommentsMode       string
+	ConfigOverwriteConfig    bool
+	ConfigOverwriteListPages bool
+	ConfigPagerSize          int
 }
 
 func NewWpExport(logger *logging.Logger) *WpExport {
@@ -40,6 +100,30 @@ func NewWpExport(logger *logging.Logger) *WpExport {
 	wp_export.ConfigNoDownloads = false
 	wp_export.ConfigNoComments = false
 	wp_export.ConfigOutputDir = "build"
+	wp_export.ConfigAttachmentTypes = DefaultAttachmentTypes()
+	wp_export.ConfigStaticUploadsPaths = false
+	wp_export.ConfigUploadsBacklinks = UPLOADS_BACKLINKS_NONE
+	wp_export.ConfigArchiveTypes = DefaultArchiveTypes()
+	wp_export.ConfigPathTemplates = DefaultPathTemplates()
+	wp_export.ConfigTaxonomyNames = DefaultTaxonomyNames()
+	wp_export.ConfigPageTemplates = DefaultPageTemplates()
+	wp_export.ConfigUnapprovedComments = false
+	wp_export.ConfigPingbacks = PINGBACKS_SEPARATE
+	wp_export.ConfigCommentsFormats = []string{COMMENTS_YAML}
+	wp_export.ConfigRemark42Site = "remark"
+	wp_export.ConfigCommentAuthorUrls = COMMENT_URLS_NOFOLLOW
+	wp_export.ConfigAnonymizeComments = false
+	wp_export.ConfigAnonymizeSalt = ""
+	wp_export.ConfigCommentsContent = COMMENTS_CONTENT_MARKDOWN
+	wp_export.ConfigCommentsMode = COMMENTS_MODE_BUNDLE
+	wp_export.ConfigOverwriteConfig = false
+	wp_export.ConfigOverwriteListPages = false
+	wp_export.ConfigPagerSize = 10
+	wp_export.static_media = map[string]string{}
+	wp_export.permalink_patterns = map[string]map[string]int{}
+	wp_export.taxonomies = map[string]string{}
+	wp_export.LinkRewriters = wp_export.DefaultLinkRewriters()
+	wp_export.CommentWriters = wp_export.DefaultCommentWriters()
 
 	wp_export.log.Debug("New instance of wordpress export created")
 
@@ -83,6 +167,10 @@ func (w *WpExport) ReadWpExport(file_path string) error {
 		w.channel = &rss.Channels[0]
 	} else {
 		w.channel.Items = append(w.channel.Items, rss.Channels[0].Items...)
+		w.channel.Categories = append(w.channel.Categories, rss.Channels[0].Categories...)
+		w.channel.Tags = append(w.channel.Tags, rss.Channels[0].Tags...)
+		w.channel.Terms = append(w.channel.Terms, rss.Channels[0].Terms...)
+		w.channel.Authors = append(w.channel.Authors, rss.Channels[0].Authors...)
 		w.log.Infof("Parsed channel addex to existing channel, new channel size is %d", len(w.channel.Items))
 	}
 
@@ -148,6 +236,11 @@ func (w *WpExport) FindParentItems(item *Item) []*Item {
 	return result
 }
 
+// check if item is exported as standalone content (post or page)
+func (w *WpExport) IsExported(item *Item) bool {
+	return item.Type == "post" || item.Type == "page"
+}
+
 func (w *WpExport) ensure_dir(path string) {
 	w.log.Debugf("Ensuring directory %s exists", path)
 	err := os.MkdirAll(path, os.ModePerm)
@@ -167,14 +260,97 @@ func (w *WpExport) Export() error {
 		return nil
 	}
 
+	switch w.ConfigUploadsBacklinks {
+	case UPLOADS_BACKLINKS_NONE, UPLOADS_BACKLINKS_COPY, UPLOADS_BACKLINKS_SYMLINK, UPLOADS_BACKLINKS_REDIRECT:
+	default:
+		return fmt.Errorf("Unknown uploads backlinks mode: %s", w.ConfigUploadsBacklinks)
+	}
+
+	switch w.ConfigPingbacks {
+	case PINGBACKS_NONE, PINGBACKS_SEPARATE, PINGBACKS_INLINE:
+	default:
+		return fmt.Errorf("Unknown pingbacks mode: %s", w.ConfigPingbacks)
+	}
+
+	switch w.ConfigCommentAuthorUrls {
+	case COMMENT_URLS_NONE, COMMENT_URLS_NOFOLLOW, COMMENT_URLS_FOLLOW:
+	default:
+		return fmt.Errorf("Unknown comment author urls mode: %s", w.ConfigCommentAuthorUrls)
+	}
+
+	switch w.ConfigCommentsContent {
+	case COMMENTS_CONTENT_MARKDOWN, COMMENTS_CONTENT_HTML:
+	default:
+		return fmt.Errorf("Unknown comments content format: %s", w.ConfigCommentsContent)
+	}
+
+	switch w.ConfigCommentsMode {
+	case COMMENTS_MODE_BUNDLE, COMMENTS_MODE_BODY, COMMENTS_MODE_DATA, COMMENTS_MODE_FRONTMATTER:
+	default:
+		return fmt.Errorf("Unknown comments mode: %s", w.ConfigCommentsMode)
+	}
+
+	for i := 0; i < len(w.ConfigCommentsFormats); i++ {
+		if _, ok := w.CommentWriters[w.ConfigCommentsFormats[i]]; !ok {
+			return fmt.Errorf("Unknown comments format: %s", w.ConfigCommentsFormats[i])
+		}
+	}
+
+	// pseudonyms could be linked to authors by anyone knowing the salt, so
+	// random one is used unless salt is given
+	if w.ConfigAnonymizeComments && w.ConfigAnonymizeSalt == "" {
+		salt := make([]byte, 16)
+		_, err := rand.Read(salt)
+		w.check(err)
+		w.ConfigAnonymizeSalt = hex.EncodeToString(salt)
+		w.log.Infof("No salt of comment pseudonyms set, using random one (pseudonyms differ between exports)")
+	}
+
+	// both store comments of item in data/comments/<content path>
+	if w.ConfigCommentsMode == COMMENTS_MODE_DATA && w.hasCommentsFormat(COMMENTS_STATICMAN) {
+		return fmt.Errorf("Comments mode %s can't be combined with %s format", COMMENTS_MODE_DATA, COMMENTS_STATICMAN)
+	}
+
+	for i := 0; i < len(w.ConfigRedirectFormats); i++ {
+		switch w.ConfigRedirectFormats[i] {
+		case REDIRECTS_ALIASES, REDIRECTS_NETLIFY, REDIRECTS_NGINX, REDIRECTS_APACHE, REDIRECTS_CSV:
+		default:
+			return fmt.Errorf("Unknown redirects format: %s", w.ConfigRedirectFormats[i])
+		}
+	}
+
+	// redirects of uploads need some redirect map
+	if w.ConfigUploadsBacklinks == UPLOADS_BACKLINKS_REDIRECT &&
+		!w.hasRedirectFormat(REDIRECTS_NETLIFY) && !w.hasRedirectFormat(REDIRECTS_NGINX) &&
+		!w.hasRedirectFormat(REDIRECTS_APACHE) && !w.hasRedirectFormat(REDIRECTS_CSV) {
+		w.log.Infof("No redirects format for uploads backlinks specified, using %s", REDIRECTS_NETLIFY)
+		w.ConfigRedirectFormats = append(w.ConfigRedirectFormats, REDIRECTS_NETLIFY)
+	}
+
+	if err := w.checkTaxonomyNames(); err != nil {
+		return err
+	}
+
 	w.prepareDirs()
 
+	if err := w.prepareItemDirs(); err != nil {
+		return err
+	}
+
+	w.prepareSections()
+
+	w.prepareSiteHosts()
+
+	w.prepareStaticMedia()
+
+	w.buildLinkIndex()
+
 	ch := w.channel
 	for i := 0; i < len(ch.Items); i++ {
 		item := ch.Items[i]
 
 		// skip media, custom types, etc.
-		if item.Type != "post" && item.Type != "page" {
+		if !w.IsExported(&item) {
 			continue
 		}
 
@@ -185,30 +361,47 @@ func (w *WpExport) Export() error {
 		front_matter := HugoFrontMatter{}
 		front_matter.Title = item.Title
 		front_matter.Date = item.PostDate.Format("2006-01-02")
-		front_matter.Slug = item.Name
+		front_matter.Slug = w.getItemSlug(&item)
+
+		w.prepareItemPermalink(&item, &front_matter)
+
+		w.prepareItemLayout(&item, &front_matter)
 
 		w.prepareItemTaxonomies(&item, &front_matter)
 
 		w.prepareItemAttachments(&item, &front_matter, item_dir)
 
-		w.prepareItemFeaturedImage(&item, &front_matter)
+		w.prepareItemFeaturedImage(&item, &front_matter, item_dir)
 
-		// first check if list index already exists in item directory
-		// (as a result of previous hierarchy conversions). If exists, use it
-		// instead of page bundle file
-		index_file := "index.md"
-		if _, err := os.Stat(filepath.Join(item_dir, "_index.md")); err == nil {
-			w.log.Debugf("List index file already exists in %d, keeping existing index", item_dir)
-			index_file = "_index.md"
-		}
+		item_media := w.prepareItemEmbeddedImages(&item, &front_matter, item_dir)
+
+		w.prepareItemRedirects(&item, &front_matter, item_dir)
+
+		// items containing other items are written as list indexes
+		index_file := w.getItemIndexFile(item_dir)
 
 		file_path := filepath.Join(item_dir, index_file)
-		w.writeItem(&item, &front_matter, file_path)
 
-		file_path = filepath.Join(item_dir, "comments.yaml")
-		w.writeItemComments(&item, file_path)
+		// comments could be part of front matter, so they are prepared
+		// before item is written
+		comment_thread := w.prepareItemComments(&item, &front_matter, item_dir)
+
+		w.writeItem(&item, &front_matter, item_media, file_path)
+
+		if comment_thread != nil {
+			comment_thread.File = file_path
+			w.exportCommentThread(comment_thread)
+		}
 	}
 
+	w.writeTermPages()
+
+	w.writeCommentThreads()
+
+	w.writeRedirects()
+
+	w.writeSiteConfig()
+
 	return nil
 }
 
@@ -225,47 +418,19 @@ func (w *WpExport) prepareDirs() {
 
 	w.hugo_pages = filepath.Join(w.hugo_content, "pages")
 	w.ensure_dir(w.hugo_pages)
+
+	w.hugo_static = filepath.Join(w.hugo_root, "static")
+	w.ensure_dir(w.hugo_static)
 }
 
-func (w *WpExport) prepareItemDir(item *Item) string {
+// get directory of item bundle as rendered by path templates
+func (w *WpExport) getItemDir(item *Item) string {
+	return w.item_dirs[item.Id]
+}
 
-	// construct file path, starting with proper dir
-	var file_path string
-	switch item.Type {
-	case "post":
-		// create directory derived from post date
-		file_path = filepath.Join(w.hugo_posts, item.PostDate.Format("2006"))
-		file_path = filepath.Join(file_path, item.PostDate.Format("2006_01_02_")+item.Name)
-
-	case "page":
-		file_path = w.hugo_pages
-
-		// look for parent pages and build appropriate directory hierarchy
-		// including _index.md files to properly configure page lists and bundles
-		parents := w.FindParentItems(item)
-
-		// if some parents exists for given page
-		if len(parents) > 0 {
-			// loop through parents top down
-			for i := len(parents) - 1; i >= 0; i-- {
-
-				// ensure dir
-				file_path = filepath.Join(file_path, parents[i].Name)
-				w.ensure_dir(file_path)
-
-				// create list index file
-				if _, err := os.Stat(filepath.Join(file_path, "index.md")); err == nil {
-					// rename index.md to _index.md
-					os.Rename(filepath.Join(file_path, "index.md"), filepath.Join(file_path, "_index.md"))
-					w.log.Infof("Renaming %s to $s", filepath.Join(file_path, "index.md"), filepath.Join(file_path, "_index.md"))
-				} else {
-					w.touchFile(filepath.Join(file_path, "_index.md"))
-				}
-			}
-		}
+func (w *WpExport) prepareItemDir(item *Item) string {
 
-		file_path = filepath.Join(file_path, item.Name)
-	}
+	file_path := w.getItemDir(item)
 
 	// create single directory for each post/page since we need a bundle (to
 	// be able to store attachments)
@@ -274,80 +439,84 @@ func (w *WpExport) prepareItemDir(item *Item) string {
 	return file_path
 }
 
+// get site url of item (relative to site root) as generated by hugo from
+// url in front matter or from content dir structure and slug
+func (w *WpExport) getItemUrl(item_dir string, fm *HugoFrontMatter) string {
+	if fm.Url != "" {
+		return fm.Url
+	}
+
+	rel_dir, err := filepath.Rel(w.hugo_content, item_dir)
+	w.check(err)
+
+	result := "/" + filepath.ToSlash(rel_dir)
+
+	// slug replaces last part of url
+	if fm.Slug != "" {
+		result = path.Join(path.Dir(result), fm.Slug)
+	}
+
+	return result + "/"
+}
+
 func (w *WpExport) prepareItemAttachments(item *Item, fh *HugoFrontMatter, item_dir string) {
 	attachments := w.FindAttachments(item.Id)
 
 	for i := 0; i < len(attachments); i++ {
 		a := attachments[i]
 		file_name := path.Base(a.AttachmentUrl)
-		file_ext := strings.ToLower(filepath.Ext(file_name))
 		target_file_name := strings.ToLower(file_name)
 
 		w.log.Debugf("Processing attachment %s", file_name)
 
-		switch file_ext {
-		case ".jpg", ".jpeg", ".png", ".gif":
-
-			r := HugoFrontMatterResource{
-				Src:    filepath.Join(ITEM_IMAGES_DIR, target_file_name),
-				Title:  a.Content,
-				Params: make(map[string]interface{}),
-			}
-
-			target_dir := filepath.Join(item_dir, ITEM_IMAGES_DIR)
-			w.ensure_dir(target_dir)
+		attachment_type := w.getAttachmentType(file_name)
 
-			target_file_path := filepath.Join(target_dir, target_file_name)
+		target_dir := filepath.Join(item_dir, attachment_type.Dir)
+		w.ensure_dir(target_dir)
 
-			// fetch file and store it
-			w.downloadFile(a.AttachmentUrl, target_file_path)
+		target_file_path := filepath.Join(target_dir, target_file_name)
 
-			r.Params["weight"] = a.MenuOrder
-			fh.Resources = append(fh.Resources, r)
+		// fetch file and store it
+		w.downloadFile(a.AttachmentUrl, target_file_path)
 
-		case ".gpx":
+		// keep original upload url working
+		site_path := path.Join(w.getItemUrl(item_dir, fh), attachment_type.Dir, target_file_name)
+		w.prepareUploadsBacklink(a.AttachmentUrl, target_file_path, site_path)
 
-			target_dir := filepath.Join(item_dir, "gpx")
-			w.ensure_dir(target_dir)
-
-			target_file_path := filepath.Join(target_dir, target_file_name)
-
-			// fetch file and store it
-			w.downloadFile(a.AttachmentUrl, target_file_path)
-
-		case ".pdf":
-
-			target_dir := filepath.Join(item_dir, "docs")
-			w.ensure_dir(target_dir)
-
-			target_file_path := filepath.Join(target_dir, target_file_name)
-
-			// fetch file and store it
-			w.downloadFile(a.AttachmentUrl, target_file_path)
-
-		default:
-			w.log.Warningf("Unknown attachment type %s (%s)", file_name, a.AttachmentUrl)
+		r := HugoFrontMatterResource{
+			Src:    filepath.Join(attachment_type.Dir, target_file_name),
+			Title:  a.Content,
+			Params: make(map[string]interface{}),
+			Source: a.AttachmentUrl,
 		}
-
+		r.Params["type"] = attachment_type.Kind
+		r.Params["weight"] = a.MenuOrder
+		fh.Resources = append(fh.Resources, r)
 	}
 }
 
 func (w *WpExport) prepareItemTaxonomies(item *Item, fm *HugoFrontMatter) {
 
-	taxonomies, err := item.GetTaxonomies()
-	w.check(err)
+	taxonomies := item.GetTaxonomies(w.ConfigTaxonomyNames)
 
-	if len(taxonomies["tags"]) > 0 {
-		fm.Tags = taxonomies["tags"]
+	for i := 0; i < len(item.Categories); i++ {
+		w.addTaxonomy(item.Categories[i].Domain)
 	}
 
-	if len(taxonomies["categories"]) > 0 {
-		fm.Categories = taxonomies["categories"]
+	// posts are listed in author archives, authors could be also assigned
+	// by plugins as terms (e.g. co-authors plus)
+	authors := w.getTaxonomyName(TAXONOMY_AUTHOR)
+	if _, ok := taxonomies[authors]; !ok && item.Type == "post" && item.Creator != "" {
+		taxonomies[authors] = []string{NormalizeSlug(item.Creator)}
+		w.addTaxonomy(TAXONOMY_AUTHOR)
 	}
 
+	if len(taxonomies) > 0 {
+		fm.Taxonomies = taxonomies
+	}
 }
 
-func (w *WpExport) writeItem(item *Item, fm *HugoFrontMatter, file_path string) {
+func (w *WpExport) writeItem(item *Item, fm *HugoFrontMatter, item_media map[string]string, file_path string) {
 
 	w.log.Debugf("Writing item data to file: %s", file_path)
 
@@ -366,56 +535,75 @@ func (w *WpExport) writeItem(item *Item, fm *HugoFrontMatter, file_path string)
 	w.file_write_str(f, "---\n\n")
 
 	// process item content
-	converter := md.NewConverter("", true, nil)
-
-	content_markdown, err := converter.ConvertString(item.Content)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(item.Content))
 	w.check(err)
 
-	// fix all image links
-	content_markdown = w.fixLinks(content_markdown)
+	// fix all links
+	w.rewriteLinks(item, fm, item_media, doc.Selection)
+
+	content_markdown := w.newConverter().Convert(doc.Selection)
 
 	w.file_write_str(f, content_markdown)
 }
 
-func (w *WpExport) writeItemComments(item *Item, file_path string) {
-
-	w.log.Debugf("Writing item comments data to file: %s", file_path)
+// filter and thread comments of item, nil is returned if there is nothing
+// to export
+func (w *WpExport) prepareItemComments(item *Item, fm *HugoFrontMatter, item_dir string) *CommentThread {
 
 	// if comments sould be added
 	if w.ConfigNoComments {
-		return
+		return nil
 	}
 
-	if len(item.Comments) == 0 {
-		return
+	comments, pingbacks := w.filterComments(item)
+
+	switch w.ConfigPingbacks {
+	case PINGBACKS_INLINE:
+		comments = append(comments, pingbacks...)
+		pingbacks = nil
+	case PINGBACKS_NONE:
+		pingbacks = nil
 	}
 
-	item.Comments = w.buildCommentsTree(item.Comments, 0)
+	if len(comments) == 0 && len(pingbacks) == 0 {
+		return nil
+	}
 
-	comments_bytes, err := yaml.Marshal(item.Comments)
-	w.check(err)
+	item.Comments = w.buildCommentsTree(comments, 0)
 
-	f, err := os.Create(file_path)
-	w.check(err)
-	// It’s idiomatic to defer a Close immediately after opening a file.
-	defer f.Close()
-	_, err = f.Write(comments_bytes)
+	if w.hasCommentsFormat(COMMENTS_YAML) && w.ConfigCommentsMode == COMMENTS_MODE_FRONTMATTER {
+		fm.Comments = item.Comments
+		fm.Pingbacks = pingbacks
+	}
+
+	return &CommentThread{
+		Item:      item,
+		Url:       w.getItemUrl(item_dir, fm),
+		Dir:       item_dir,
+		Key:       w.getItemKey(item_dir),
+		Comments:  item.Comments,
+		Pingbacks: pingbacks,
+	}
+}
+
+// get content path of item bundle (relative to content dir, with slashes)
+func (w *WpExport) getItemKey(item_dir string) string {
+	rel, err := filepath.Rel(w.hugo_content, item_dir)
 	w.check(err)
+	return filepath.ToSlash(rel)
 }
 
-func (w *WpExport) touchFile(file_path string) {
+func (w *WpExport) writeYaml(data interface{}, file_path string) {
 
-	// check if local file exists
-	if _, err := os.Stat(file_path); err == nil {
-		return
-	}
+	data_bytes, err := yaml.Marshal(data)
+	w.check(err)
 
-	// file doesn't exist, let's create it
 	f, err := os.Create(file_path)
 	w.check(err)
-
 	// It’s idiomatic to defer a Close immediately after opening a file.
 	defer f.Close()
+	_, err = f.Write(data_bytes)
+	w.check(err)
 }
 
 // DownloadFile will download a url to a local file. It's efficient because it will
@@ -448,9 +636,24 @@ func (w *WpExport) downloadFile(url string, file_path string) {
 	w.check(err)
 }
 
+// copy content of local file, existing target file is overwritten
+func (w *WpExport) copyFile(src_path string, dst_path string) {
+
+	in, err := os.Open(src_path)
+	w.check(err)
+	defer in.Close()
+
+	out, err := os.Create(dst_path)
+	w.check(err)
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	w.check(err)
+}
+
 // look for featured image in item metadata
 // must be called afther attachements are converted into item resources
-func (w *WpExport) prepareItemFeaturedImage(item *Item, fm *HugoFrontMatter) {
+func (w *WpExport) prepareItemFeaturedImage(item *Item, fm *HugoFrontMatter, item_dir string) {
 	for i := 0; i < len(item.Meta); i++ {
 		if item.Meta[i].Key == "_thumbnail_id" {
 			// we have media id, look for the appropriate item
@@ -458,24 +661,51 @@ func (w *WpExport) prepareItemFeaturedImage(item *Item, fm *HugoFrontMatter) {
 			w.check(err)
 			featured_image_item := w.FindItem(int_value)
 			// if media item was found
-			if featured_image_item != nil {
-				// consturct file path as is used in both front header resources and post/page bundles
-				file_name := filepath.Join(ITEM_IMAGES_DIR, strings.ToLower(path.Base(featured_image_item.AttachmentUrl)))
-				// look for file name in current item attachments, this is necessary check
-				// since we cannot convert references to media that are not attached to given item
-				// it is possible, but it will require to fetch one more additional image and put it into resources
-				for j := 0; j < len(fm.Resources); j++ {
-					if fm.Resources[j].Src == file_name {
-						// we finally identified valid featured image
+			if featured_image_item != nil && featured_image_item.AttachmentUrl != "" {
+				source_url, err := url.Parse(featured_image_item.AttachmentUrl)
+				w.check(err)
+				source := w.getCanonicalUrl(source_url)
 
-						// let's store it in item parameter
-						fm.FeaturedImage = file_name
-
-						// and also mark appripriate resource by param
+				// look for media in current item attachments and mark
+				// appripriate resource by param
+				for j := 0; j < len(fm.Resources); j++ {
+					if w.isResourceSource(&fm.Resources[j], source) {
 						fm.Resources[j].Params["featured"] = true
-						break
+						fm.FeaturedImage = fm.Resources[j].Src
+						return
 					}
 				}
+
+				// media is not attached to given item (it is attached to
+				// another item or not attached at all), so it has to be
+				// fetched into current bundle
+				w.log.Debugf("Fetching featured image %s not attached to %s (%d)", featured_image_item.AttachmentUrl, item.Title, item.Id)
+
+				// construct file path as is used in both front header resources and post/page bundles
+				attachment_type := w.getAttachmentType(path.Base(featured_image_item.AttachmentUrl))
+				target_file_name := w.getUniqueResourceName(fm, attachment_type.Dir, strings.ToLower(path.Base(featured_image_item.AttachmentUrl)))
+				file_name := filepath.Join(attachment_type.Dir, target_file_name)
+
+				// let's store it in item parameter
+				fm.FeaturedImage = file_name
+
+				target_dir := filepath.Join(item_dir, attachment_type.Dir)
+				w.ensure_dir(target_dir)
+
+				w.downloadFile(featured_image_item.AttachmentUrl, filepath.Join(target_dir, target_file_name))
+
+				r := HugoFrontMatterResource{
+					Src:    file_name,
+					Title:  featured_image_item.Content,
+					Params: make(map[string]interface{}),
+					Source: featured_image_item.AttachmentUrl,
+				}
+				r.Params["type"] = attachment_type.Kind
+				r.Params["weight"] = len(fm.Resources)
+				r.Params["featured"] = true
+				fm.Resources = append(fm.Resources, r)
+			} else {
+				w.log.Warningf("Featured image %d of %s (%d) not found", int_value, item.Title, item.Id)
 			}
 
 			break
@@ -502,35 +732,3 @@ func (w *WpExport) buildCommentsTree(comments []ItemComment, parent_id int) []It
 
 	return result
 }
-
-func (w *WpExport) fixLinks(md string) string {
-
-	url := regexp.QuoteMeta(w.channel.Link)
-
-	// All image links have the following form:
-	// [![](https://some.domain/wp-content/uploads/2005/3849/filename.jpg)](https://some.domain/wp-content/uploads/2005/3849/filename.jpg)
-	fix_images := regexp.MustCompile(`\[!\[\]\([^)]+\)\]\(` + url + `/wp-content/.*/([^./)]+\.[[:alpha:]]+)\)`)
-	md = fix_images.ReplaceAllString(md, "{{<figure src=\"images/$1\">}}")
-
-	// All simple image links have the following form:
-	// ![](https://some.domain/wp-content/uploads/kolo_prumer_diagram.png)
-	fix_images_simple := regexp.MustCompile(`!\[\]\(` + url + `/wp-content/.*/([^./)]+\.[[:alpha:]]+)\)`)
-	md = fix_images_simple.ReplaceAllString(md, "{{<figure src=\"images/$1\">}}")
-
-	// All media links have the following form:
-	// [Eustachova chata](https://some.domain/wp-content/uploads/file.pdf)
-	fix_media_links := regexp.MustCompile(`\[([^]]+)\]\(` + url + `/wp-content/[^)]*/([^./)]+\.[[:alpha:]]+)\)`)
-	md = fix_media_links.ReplaceAllString(md, `[$1]({{<ref "/docs/$2" >}})`)
-
-	// All category links have the following form:
-	// [music](https://some.domain/category/music/)
-	fix_cat_links := regexp.MustCompile(`\[([^]]+)\]\(` + url + `/category/(.*)\)`)
-	md = fix_cat_links.ReplaceAllString(md, `[$1]({{<ref "/categories/$2" >}})`)
-
-	// All links have the following form:
-	// [something](https://some.domain/path/path/path/)
-	fix_links := regexp.MustCompile(`\[([^]]+)\]\(` + url + `/(.*)\)`)
-	md = fix_links.ReplaceAllString(md, `[$1]({{<ref "/$2" >}})`)
-
-	return md
-}